@@ -6,6 +6,7 @@ require (
 	go.mongodb.org/mongo-driver/v2 v2.2.2
 	golang.org/x/crypto v0.33.0
 	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -17,5 +18,4 @@ require (
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/sync v0.13.0 // indirect
 	golang.org/x/text v0.22.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
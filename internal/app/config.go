@@ -21,7 +21,14 @@ type Environment struct {
 	SERVER_HTTPS_KEY_PATH  string
 	SERVER_TIMEOUT         int
 
-	SESSION_LIFETIME int
+	SESSION_LIFETIME       int // minutos de vida del access token
+	REFRESH_TOKEN_LIFETIME int // minutos de vida del refresh token
+
+	AUTH_DRIVER       string // database (token opaco guardado en mongo) o jwt (token firmado)
+	JWT_ALGORITHM     string // HS256 (firma con APP_KEY), RS256 o EdDSA (llaves en JWT_KEYS_PATH)
+	JWT_KEY_ID        string // kid con el que se firman los tokens nuevos
+	JWT_KEYS_PATH     string // carpeta con <kid>.key (privada) y <kid>.pub (publica) en PEM
+	JWT_PREVIOUS_KEYS string // HS256: llaves anteriores kid:secreto separadas por coma, solo para verificar
 
 	DB_DATABASE          string
 	DB_CONNECTION_STRING string
@@ -59,7 +66,14 @@ var Env = Environment{
 	SERVER_HTTPS_KEY_PATH:  "certs/server.key",
 	SERVER_TIMEOUT:         60,
 
-	SESSION_LIFETIME: 60,
+	SESSION_LIFETIME:       60,
+	REFRESH_TOKEN_LIFETIME: 60 * 24 * 30,
+
+	AUTH_DRIVER:       "database",
+	JWT_ALGORITHM:     "HS256",
+	JWT_KEY_ID:        "1",
+	JWT_KEYS_PATH:     "keys/jwt",
+	JWT_PREVIOUS_KEYS: "",
 
 	DB_DATABASE:          "sample_mflix",
 	DB_CONNECTION_STRING: "mongodb://localhost:27017",
@@ -160,10 +174,25 @@ func LoadEnv(filepath ...string) {
 				Env.SERVER_TIMEOUT = timeout
 			}
 		case "SESSION_LIFETIME":
-			duration, e := strconv.Atoi(value)
-			if e != nil {
+			if duration, e := strconv.Atoi(value); e == nil {
 				Env.SESSION_LIFETIME = duration
 			}
+		case "REFRESH_TOKEN_LIFETIME":
+			if duration, e := strconv.Atoi(value); e == nil {
+				Env.REFRESH_TOKEN_LIFETIME = duration
+			}
+
+		case "AUTH_DRIVER":
+			Env.AUTH_DRIVER = strings.ToLower(value)
+		case "JWT_ALGORITHM":
+			Env.JWT_ALGORITHM = value
+		case "JWT_KEY_ID":
+			Env.JWT_KEY_ID = value
+		case "JWT_KEYS_PATH":
+			Env.JWT_KEYS_PATH = value
+		case "JWT_PREVIOUS_KEYS":
+			Env.JWT_PREVIOUS_KEYS = value
+
 		case "DB_MIGRATION_ENABLE":
 			Env.DB_MIGRATION_ENABLE = false
 			if strings.ToLower(value) == "true" {
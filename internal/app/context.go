@@ -5,8 +5,11 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -43,10 +46,70 @@ func NewHttpContext(w http.ResponseWriter, r *http.Request) *HttpContext {
 	}
 }
 
+// IP del cliente, si viene de un proxy se toma X-Forwarded-For o X-Real-IP.
+// Los encabezados los puede enviar cualquiera, usela solo para mostrar, no para permisos
+func (ctx *HttpContext) IP() string {
+	if forwarded := ctx.Request.Header.Get("X-Forwarded-For"); forwarded != "" {
+		ip, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(ip)
+	}
+	if ip := ctx.Request.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	if host, _, er := net.SplitHostPort(ctx.Request.RemoteAddr); er == nil {
+		return host
+	}
+	return ctx.Request.RemoteAddr
+}
+
+func (ctx *HttpContext) UserAgent() string {
+	return ctx.Request.UserAgent()
+}
+
 func (ctx *HttpContext) Lang() string {
 	return ctx.Request.Header.Get("Accept-Language")
 }
 
+// Locale retorna el idioma preferido del Accept-Language (ej: "es-CO,es;q=0.9" → "es-CO"), si no viene retorna "en"
+func (ctx *HttpContext) Locale() string {
+	if locales := ctx.Locales(); len(locales) > 0 {
+		return locales[0]
+	}
+	return "en"
+}
+
+// Locales retorna los idiomas del Accept-Language ordenados por preferencia (q), sin los de q=0 ni el *
+func (ctx *HttpContext) Locales() []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	tags := []weighted{}
+	for _, part := range strings.Split(ctx.Lang(), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, er := strconv.ParseFloat(value, 64); er == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	locales := make([]string, len(tags))
+	for i, t := range tags {
+		locales[i] = t.tag
+	}
+	return locales
+}
+
 func (ctx *HttpContext) GetBody(request any) Error {
 	decoder := json.NewDecoder(ctx.Request.Body)
 	if err := decoder.Decode(request); err != nil {
@@ -176,6 +239,21 @@ func (ctx *HttpContext) ResponseCSV(fileName string, data any, comma ...rune) {
 		elemType = elemType.Elem()
 	}
 
+	headers, fields := csvColumns(elemType)
+	writer.Write(headers)
+
+	for i := 0; i < val.Len(); i++ {
+		writer.Write(csvRecord(val.Index(i), fields))
+	}
+	writer.Flush()
+
+	ctx.Writer.Header().Set("Content-Type", "text/csv")
+	ctx.Writer.Header().Set("Content-Disposition", "attachment;filename="+fileName+".csv")
+	ctx.Writer.Write(buffer.Bytes())
+}
+
+// csvColumns retorna los encabezados (tag json) y los indices de los campos que van al csv
+func csvColumns(elemType reflect.Type) ([]string, []int) {
 	var headers []string
 	var fields []int
 
@@ -189,52 +267,47 @@ func (ctx *HttpContext) ResponseCSV(fileName string, data any, comma ...rune) {
 		headers = append(headers, tag)
 		fields = append(fields, i)
 	}
-	writer.Write(headers)
+	return headers, fields
+}
 
-	for i := 0; i < val.Len(); i++ {
-		var record []string
-		elem := val.Index(i)
-		if elem.Kind() == reflect.Ptr {
-			elem = elem.Elem()
-		}
+// csvRecord convierte un struct (o puntero a struct) en una fila del csv
+func csvRecord(elem reflect.Value, fields []int) []string {
+	var record []string
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
 
-		for _, j := range fields {
-			fieldVal := elem.Field(j)
+	for _, j := range fields {
+		fieldVal := elem.Field(j)
 
-			if fieldVal.Type() == reflect.TypeOf(bson.ObjectID{}) {
-				objID := fieldVal.Interface().(bson.ObjectID)
-				record = append(record, objID.Hex()) // sin comillas manuales
-				continue
-			}
+		if fieldVal.Type() == reflect.TypeOf(bson.ObjectID{}) {
+			objID := fieldVal.Interface().(bson.ObjectID)
+			record = append(record, objID.Hex()) // sin comillas manuales
+			continue
+		}
 
-			switch fieldVal.Kind() {
-			case reflect.String:
-				record = append(record, fieldVal.String())
-			case reflect.Int, reflect.Int64:
-				record = append(record, fmt.Sprintf("%d", fieldVal.Int()))
-			case reflect.Float64:
-				record = append(record, fmt.Sprintf("%f", fieldVal.Float()))
-			case reflect.Bool:
-				record = append(record, fmt.Sprintf("%t", fieldVal.Bool()))
-			case reflect.Struct:
-				if t, ok := fieldVal.Interface().(time.Time); ok {
-					record = append(record, t.Format(time.RFC3339))
-				} else {
-					jsonVal, _ := json.Marshal(fieldVal.Interface())
-					record = append(record, string(jsonVal))
-				}
-			case reflect.Slice, reflect.Map, reflect.Array:
+		switch fieldVal.Kind() {
+		case reflect.String:
+			record = append(record, fieldVal.String())
+		case reflect.Int, reflect.Int64:
+			record = append(record, fmt.Sprintf("%d", fieldVal.Int()))
+		case reflect.Float64:
+			record = append(record, fmt.Sprintf("%f", fieldVal.Float()))
+		case reflect.Bool:
+			record = append(record, fmt.Sprintf("%t", fieldVal.Bool()))
+		case reflect.Struct:
+			if t, ok := fieldVal.Interface().(time.Time); ok {
+				record = append(record, t.Format(time.RFC3339))
+			} else {
 				jsonVal, _ := json.Marshal(fieldVal.Interface())
 				record = append(record, string(jsonVal))
-			default:
-				record = append(record, fmt.Sprintf("%v", fieldVal.Interface()))
 			}
+		case reflect.Slice, reflect.Map, reflect.Array:
+			jsonVal, _ := json.Marshal(fieldVal.Interface())
+			record = append(record, string(jsonVal))
+		default:
+			record = append(record, fmt.Sprintf("%v", fieldVal.Interface()))
 		}
-		writer.Write(record)
 	}
-	writer.Flush()
-
-	ctx.Writer.Header().Set("Content-Type", "text/csv")
-	ctx.Writer.Header().Set("Content-Disposition", "attachment;filename="+fileName+".csv")
-	ctx.Writer.Write(buffer.Bytes())
+	return record
 }
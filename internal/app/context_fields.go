@@ -0,0 +1,165 @@
+package app
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Fields lee ?fields=email,profile.nickname, solo se permiten los campos de la lista (nombres json).
+// Si no viene el parametro retorna nil y la respuesta lleva todos los campos.
+func (ctx *HttpContext) Fields(allowed ...string) ([]string, Error) {
+	return ctx.queryList("fields", "The field :field is not allowed in fields", allowed)
+}
+
+// Include lee ?include=roles,permissions, solo se permiten las relaciones de la lista
+func (ctx *HttpContext) Include(allowed ...string) ([]string, Error) {
+	return ctx.queryList("include", "The relation :field is not allowed in include", allowed)
+}
+
+// Select lee ?fields= y ?include= en el QueryFilter con las listas permitidas del endpoint.
+// Los $lookup de qf.Include los pone el controlador en qf.Lookups (ej: user.With(qf.Include...)).
+func (qf *QueryFilter) Select(ctx *HttpContext, fields []string, include []string) Error {
+	var err Error
+	if qf.Fields, err = ctx.Fields(fields...); err != nil {
+		return err
+	}
+	if qf.Include, err = ctx.Include(include...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ctx *HttpContext) queryList(param string, message string, allowed []string) ([]string, Error) {
+	values, ok := ctx.Request.URL.Query()[param]
+	if !ok {
+		return nil, nil
+	}
+	list := []string{}
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "" || slices.Contains(list, item) {
+				continue
+			}
+			if !slices.Contains(allowed, item) {
+				return nil, Errors.BadRequestf(message, Entry{"field", item})
+			}
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// ProjectFields arma el $project para traer de mongo solo los campos pedidos y las relaciones incluidas.
+// Los nombres json se traducen a bson con el registro de modelos. Retorna nil si no hay campos.
+func ProjectFields(m Model, fields []string, include ...string) bson.D {
+	return projectStage(m, fields, include)
+}
+
+// projectStage igual que ProjectFields, extra son rutas bson que se necesitan aunque no se pidan (ej: campos del cursor)
+func projectStage(m Model, fields []string, include []string, extra ...string) bson.D {
+	if len(fields) == 0 {
+		return nil
+	}
+	schema, _ := GetModel(m.CollectionName())
+
+	projection := bson.D{}
+	for _, path := range extra {
+		if path != "_id" {
+			projection = append(projection, bson.E{Key: path, Value: 1})
+		}
+	}
+	for _, field := range append(slices.Clone(fields), includeRoots(include)...) {
+		path := field
+		if field == "id" {
+			continue // _id siempre viene
+		}
+		if schema != nil {
+			if f, ok := schema.FieldByJson(field); ok {
+				path = f.Path
+			}
+		}
+		if !slices.ContainsFunc(projection, func(e bson.E) bool { return e.Key == path }) {
+			projection = append(projection, bson.E{Key: path, Value: 1})
+		}
+	}
+	// mongo rechaza profile y profile.city_id juntos (path collision), si ya viene el padre sobra el hijo
+	paths := slices.Clone(projection)
+	projection = slices.DeleteFunc(projection, func(e bson.E) bool {
+		return slices.ContainsFunc(paths, func(parent bson.E) bool {
+			return strings.HasPrefix(e.Key, parent.Key+".")
+		})
+	})
+	return bson.D{{Key: "$project", Value: projection}}
+}
+
+// SparseFields deja en la respuesta solo los campos pedidos (nombres json con punto para los anidados),
+// el id y las relaciones incluidas. data puede ser un modelo o un slice de modelos.
+func SparseFields(data any, fields []string, include ...string) (any, Error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+	raw, er := json.Marshal(data)
+	if er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+	var value any
+	if er := json.Unmarshal(raw, &value); er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+
+	keep := append([]string{"id"}, fields...)
+	keep = append(keep, includeRoots(include)...)
+	return pruneFields(value, keep), nil
+}
+
+// includeRoots deja el primer nivel de las relaciones, roles.permissions viene dentro de roles
+func includeRoots(include []string) []string {
+	roots := []string{}
+	for _, name := range include {
+		root, _, _ := strings.Cut(name, ".")
+		if !slices.Contains(roots, root) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
+func pruneFields(value any, keep []string) any {
+	if list, ok := value.([]any); ok {
+		result := make([]any, len(list))
+		for i, item := range list {
+			result[i] = pruneFields(item, keep)
+		}
+		return result
+	}
+	object, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	// se agrupan las rutas por el primer nivel, si se pide el objeto completo no se recorta
+	nested := map[string][]string{}
+	whole := map[string]bool{}
+	for _, path := range keep {
+		name, rest, isNested := strings.Cut(path, ".")
+		if isNested {
+			nested[name] = append(nested[name], rest)
+		} else {
+			whole[name] = true
+		}
+	}
+
+	result := map[string]any{}
+	for name, v := range object {
+		if whole[name] {
+			result[name] = v
+		} else if paths, ok := nested[name]; ok {
+			result[name] = pruneFields(v, paths)
+		}
+	}
+	return result
+}
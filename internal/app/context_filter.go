@@ -2,7 +2,13 @@ package app
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
@@ -26,10 +32,12 @@ var MongoFilterMap = map[string]string{
 	"lte": "$lte", // Menor o igual que
 	"<=":  "$lte", // Menor o igual que
 
-	"lk":    "$regex", // LIKE (puede usarse con opciones de case-insensitive)
-	"like":  "$regex", // LIKE
-	"ilk":   "$regex", // ILIKE (insensitive)
-	"ilike": "$regex", // ILIKE
+	"lk":     "$regex", // LIKE (el texto se escapa, busca que contenga el valor)
+	"like":   "$regex", // LIKE
+	"ilk":    "$regex", // ILIKE (insensitive)
+	"ilike":  "$regex", // ILIKE
+	"regex":  "$regex", // expresion regular sin escapar
+	"iregex": "$regex", // expresion regular sin escapar (insensitive)
 
 	"in":  "$in",  // Dentro de una lista
 	"nin": "$nin", // No dentro de una lista
@@ -42,6 +50,25 @@ var MongoFilterMap = map[string]string{
 	"bw":      "$gte_lte", // Alias de between
 }
 
+// unidades permitidas para agrupar fechas con group_by=campo:unidad
+var DATE_UNITS = []string{"year", "quarter", "month", "week", "day", "hour"}
+
+// ?trash, ?group_by y los filtros regex se habilitan pasando estas opciones en allowed de ctx.QueryFilter.
+// Un listado normal no las acepta: trash mostraria los eliminados sin la politica de la papelera,
+// group_by cambia la forma de los documentos que se decodifican en el modelo
+// y una expresion regular del cliente puede tumbar la base de datos (ReDoS)
+const QUERY_TRASH = "?trash"
+const QUERY_GROUP_BY = "?group_by"
+const QUERY_REGEX = "?regex"
+
+// maximo de caracteres de una expresion regular en regex e iregex
+const QUERY_MAX_REGEX_LENGTH = 100
+
+// maximo de registros por pagina que se puede pedir por la url
+const QUERY_MAX_PER_PAGE = 1000
+
+var queryFilterKey = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
 type Filter struct {
 	Key    string
 	Filter string
@@ -60,9 +87,15 @@ type QueryFilter struct {
 	Page            int // si es cero la paginacion es por cursor
 	PerPage         int
 	Cursor          string
-	CursorDirection int    // 1 para asc, -1 para desc (Mongo style)
-	Trash           int    // 0 para without(default) 1 para with y 2 only
-	Path            string // ruta del path para la paginacion
+	CursorDirection int          // 1 siguiente pagina, -1 pagina anterior (se toma del cursor)
+	Trash           int          // 0 para without(default) 1 para with y 2 only
+	Path            string       // ruta del path para la paginacion
+	Schema          *ModelSchema // modelo consultado, se usa para convertir los valores de los filtros
+	Where           *FilterNode  // filtro anidado con and, or y not, se combina con Filters usando and
+	Fields          []string     // campos de la respuesta (nombres json), vacio trae todos
+	Include         []string     // relaciones pedidas, se dejan completas en la respuesta
+	Lookups         []bson.D     // etapas $lookup de las relaciones pedidas
+	Timezone        string       // zona horaria del usuario para agrupar por fecha (ej: created_at:day) y leer las fechas de los filtros
 }
 
 func NewQueryFilter() *QueryFilter {
@@ -78,6 +111,170 @@ func NewQueryFilter() *QueryFilter {
 	}
 }
 
+// QueryFilter arma el filtro con los parametros de la url, solo se puede filtrar,
+// ordenar y agrupar por los campos permitidos (id se traduce a _id).
+//
+//	?filter[email][ilike]=foo&filter[city_id]=123&sort=-created_at,email&page=2&per_page=50&trash=with
+//
+// ?trash, ?group_by y los filtros regex e iregex solo se aceptan si allowed incluye QUERY_TRASH, QUERY_GROUP_BY o QUERY_REGEX.
+// Para condiciones con or y not se usa ?where= con json (ver parseWhere).
+// Si viene ?cursor la paginacion es por cursor y se ignora page, la primera pagina se pide con ?cursor=
+// y las siguientes con el next_cursor o prev_cursor de la respuesta.
+func (ctx *HttpContext) QueryFilter(allowed ...string) (*QueryFilter, Error) {
+	qf := NewQueryFilter()
+	qf.Page = 1
+	qf.Path = ctx.Request.URL.Path
+	qf.Timezone = ctx.Location().String()
+	query := ctx.Request.URL.Query()
+
+	isAllowed := func(field string) bool {
+		return slices.Contains(allowed, field)
+	}
+	fieldName := func(field string) string {
+		if field == "id" {
+			return "_id"
+		}
+		return field
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		match := queryFilterKey.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		field, operator := match[1], match[2]
+		if operator == "" {
+			operator = "eq"
+		}
+		if !isAllowed(field) {
+			return nil, Errors.BadRequestf("The field :field is not allowed to filter", Entry{"field", field})
+		}
+		for _, value := range query[key] {
+			if err := checkOperator(operator, value, allowed); err != nil {
+				return nil, err
+			}
+			qf.AppendFilter(fieldName(field), operator, value)
+		}
+	}
+
+	if where := query.Get("where"); where != "" {
+		node, err := parseWhere(where, allowed)
+		if err != nil {
+			return nil, err
+		}
+		qf.Where = node
+	}
+
+	for _, param := range query["sort"] {
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			direction := 1
+			if strings.HasPrefix(field, "-") {
+				direction = -1
+			}
+			field = strings.TrimLeft(field, "+-")
+			if !isAllowed(field) {
+				return nil, Errors.BadRequestf("The field :field is not allowed to sort", Entry{"field", field})
+			}
+			qf.AppendSort(fieldName(field), direction)
+		}
+	}
+
+	if query.Has("group_by") && !isAllowed(QUERY_GROUP_BY) {
+		return nil, Errors.BadRequestf("The group_by is not allowed in this list")
+	}
+	for _, param := range query["group_by"] {
+		for _, field := range strings.Split(param, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			// las fechas se pueden agrupar por unidad: created_at:month
+			field, unit, hasUnit := strings.Cut(field, ":")
+			if !isAllowed(field) {
+				return nil, Errors.BadRequestf("The field :field is not allowed to group", Entry{"field", field})
+			}
+			if hasUnit {
+				if !slices.Contains(DATE_UNITS, unit) {
+					return nil, Errors.BadRequestf("The date unit :unit is not supported", Entry{"unit", unit})
+				}
+				qf.AppendGrouBy(fieldName(field) + ":" + unit)
+				continue
+			}
+			qf.AppendGrouBy(fieldName(field))
+		}
+	}
+
+	if value := query.Get("per_page"); value != "" {
+		perPage, er := strconv.Atoi(value)
+		if er != nil || perPage < 1 {
+			return nil, Errors.BadRequestf("The per_page must be a positive integer")
+		}
+		qf.PerPage = min(perPage, QUERY_MAX_PER_PAGE)
+	}
+
+	if value := query.Get("page"); value != "" {
+		page, er := strconv.Atoi(value)
+		if er != nil || page < 1 {
+			return nil, Errors.BadRequestf("The page must be a positive integer")
+		}
+		qf.Page = page
+	}
+
+	if query.Has("cursor") {
+		qf.CursorPaginate()
+		if cursor := query.Get("cursor"); cursor != "" {
+			pc, err := qf.decodeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+			qf.Cursor = cursor
+			qf.CursorDirection = pc.Direction
+		}
+	}
+
+	if query.Has("trash") && !isAllowed(QUERY_TRASH) {
+		return nil, Errors.BadRequestf("The trash is not allowed in this list")
+	}
+	switch query.Get("trash") {
+	case "", "without":
+		qf.WithoutTrash()
+	case "with":
+		qf.WithTrash()
+	case "only":
+		qf.OnlyTrash()
+	default:
+		return nil, Errors.BadRequestf("The trash must be with, without or only")
+	}
+
+	return qf, nil
+}
+
+// checkOperator valida que el operador exista, regex e iregex solo con QUERY_REGEX y con un patron corto
+func checkOperator(operator string, value string, allowed []string) Error {
+	if _, ok := MongoFilterMap[operator]; !ok {
+		return Errors.BadRequestf("The filter operator :operator is not supported", Entry{"operator", operator})
+	}
+	if operator != "regex" && operator != "iregex" {
+		return nil
+	}
+	if !slices.Contains(allowed, QUERY_REGEX) {
+		return Errors.BadRequestf("The filter operator :operator is not allowed in this list", Entry{"operator", operator})
+	}
+	if len(value) > QUERY_MAX_REGEX_LENGTH {
+		return Errors.BadRequestf("The filter regex exceeds :max characters", Entry{"max", QUERY_MAX_REGEX_LENGTH})
+	}
+	return nil
+}
+
 func (qf *QueryFilter) AppendFilter(key, filter, value string) {
 	qf.Filters = append(qf.Filters, Filter{key, filter, value})
 }
@@ -177,30 +374,21 @@ func (qf *QueryFilter) All() {
 	qf.CursorDirection = 0
 }
 
-func (qf *QueryFilter) Pipeline() mongo.Pipeline {
+func (qf *QueryFilter) Pipeline() (mongo.Pipeline, Error) {
+	match, err := qf.BsonD()
+	if err != nil {
+		return nil, err
+	}
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: qf.BsonD()}},
+		{{Key: "$match", Value: match}},
 	}
 
-	if len(qf.GroupBy) > 0 {
-		groupID := bson.D{}
-		for _, field := range qf.GroupBy {
-			groupID = append(groupID, bson.E{Key: field, Value: "$" + field})
-		}
-		pipeline = append(pipeline, bson.D{
-			{Key: "$group", Value: bson.D{
-				{Key: "_id", Value: groupID},
-				{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
-			}},
-		})
-	}
-
-	if len(qf.Sort) > 0 {
-		sortDoc := bson.D{}
-		for _, s := range qf.Sort {
-			sortDoc = append(sortDoc, bson.E{Key: s.Field, Value: s.Direction})
-		}
-		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sortDoc}})
+	if stage := qf.groupStage(); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
+
+	if stage := qf.sortStage(); stage != nil {
+		pipeline = append(pipeline, stage)
 	}
 
 	if qf.Page > 0 {
@@ -212,80 +400,279 @@ func (qf *QueryFilter) Pipeline() mongo.Pipeline {
 		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: qf.PerPage}})
 	}
 
-	return pipeline
+	return pipeline, nil
 }
 
-func (qf *QueryFilter) BsonD() *bson.D {
-	filters := bson.D{}
+func (qf *QueryFilter) groupStage() bson.D {
+	if len(qf.GroupBy) == 0 {
+		return nil
+	}
+	groupID := bson.D{}
+	for _, field := range qf.GroupBy {
+		alias, expression := qf.GroupExpression(field)
+		groupID = append(groupID, bson.E{Key: alias, Value: expression})
+	}
+	return bson.D{
+		{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: groupID},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}},
+	}
+}
+
+// GroupExpression retorna el nombre y la expresion de agrupacion de un campo de GroupBy,
+// "created_at:month" agrupa por $dateTrunc y los puntos del nombre se cambian por _
+func (qf *QueryFilter) GroupExpression(groupBy string) (string, any) {
+	field, unit, hasUnit := strings.Cut(groupBy, ":")
+	alias := strings.ReplaceAll(field, ".", "_")
+	if !hasUnit {
+		return alias, "$" + field
+	}
+	trunc := bson.D{
+		{Key: "date", Value: "$" + field},
+		{Key: "unit", Value: unit},
+	}
+	// el dia y el mes dependen de la zona horaria, se agrupa segun la del usuario
+	if qf.Timezone != "" && qf.Timezone != "UTC" {
+		trunc = append(trunc, bson.E{Key: "timezone", Value: qf.Timezone})
+	}
+	return alias, bson.D{{Key: "$dateTrunc", Value: trunc}}
+}
+
+func (qf *QueryFilter) sortStage() bson.D {
+	sorts := qf.Sort
+	if qf.isCursorMode() {
+		sorts = qf.cursorSort()
+	}
+	if len(sorts) == 0 {
+		return nil
+	}
+	sortDoc := bson.D{}
+	for _, s := range sorts {
+		sortDoc = append(sortDoc, bson.E{Key: s.Field, Value: s.Direction})
+	}
+	return bson.D{{Key: "$sort", Value: sortDoc}}
+}
+
+// BsonD arma el $match, los valores se convierten al tipo del campo en el modelo (ver SetModel).
+// Si un campo aparece en varios filtros se combinan con $and.
+func (qf *QueryFilter) BsonD() (bson.D, Error) {
+	clauses := bson.D{}
 
 	switch qf.Trash {
 	case 0: // sin basura
-		filters = append(filters, bson.E{Key: "deleted_at", Value: nil})
+		clauses = append(clauses, bson.E{Key: "deleted_at", Value: nil})
 	case 2: // solo basura
-		filters = append(filters, bson.E{Key: "deleted_at", Value: bson.M{"$ne": nil}})
+		clauses = append(clauses, bson.E{Key: "deleted_at", Value: bson.M{"$ne": nil}})
 	}
 
-	if qf.Page == 0 && qf.Cursor != "" {
-		if len(qf.Sort) > 0 {
-			sortField := qf.Sort[0].Field
-			cursorOp := "$gt"
-			if qf.CursorDirection == -1 {
-				cursorOp = "$lt"
-			}
-			filters = append(filters, bson.E{Key: sortField, Value: bson.M{cursorOp: qf.Cursor}})
-		} else {
-			cursorOp := "$gt"
-			if qf.CursorDirection == -1 {
-				cursorOp = "$lt"
-			}
-			id, _ := bson.ObjectIDFromHex(qf.Cursor)
-			filters = append(filters, bson.E{Key: "_id", Value: bson.M{cursorOp: id}})
+	if qf.isCursorMode() && qf.Cursor != "" {
+		clause, err := qf.keysetClause()
+		if err != nil {
+			return nil, err
 		}
+		clauses = append(clauses, clause)
 	}
 
 	for _, f := range qf.Filters {
-		mongoOp, ok := MongoFilterMap[f.Filter]
-		if !ok {
-			continue // Operador no soportado
+		clause, err := qf.filterClause(f)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if qf.Where != nil {
+		where, err := qf.whereClause(qf.Where)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, where...)
+	}
+
+	filters := bson.D{}
+	and := bson.A{}
+	seen := map[string]bool{}
+	for _, clause := range clauses {
+		if seen[clause.Key] {
+			and = append(and, bson.D{clause})
+			continue
 		}
+		seen[clause.Key] = true
+		filters = append(filters, clause)
+	}
+	if len(and) > 0 {
+		filters = append(filters, bson.E{Key: "$and", Value: and})
+	}
+
+	return filters, nil
+}
+
+// filterClause convierte un filtro en la condicion de mongo
+func (qf *QueryFilter) filterClause(f Filter) (bson.E, Error) {
+	mongoOp, ok := MongoFilterMap[f.Filter]
+	if !ok {
+		return bson.E{}, Errors.BadRequestf("The filter operator :operator is not supported", Entry{"operator", f.Filter})
+	}
 
-		switch f.Filter {
-		case "in", "nin":
-			// Split por comas para múltiples valores
-			values := strings.Split(f.Value, ",")
-			arr := make(bson.A, 0, len(values))
-			for _, val := range values {
-				arr = append(arr, strings.TrimSpace(val))
+	switch f.Filter {
+	case "in", "nin":
+		// Split por comas para múltiples valores
+		values := strings.Split(f.Value, ",")
+		arr := make(bson.A, 0, len(values))
+		for _, val := range values {
+			v, err := qf.filterValue(f.Key, strings.TrimSpace(val))
+			if err != nil {
+				return bson.E{}, err
 			}
-			filters = append(filters, bson.E{Key: f.Key, Value: bson.M{mongoOp: arr}})
+			arr = append(arr, v)
+		}
+		return bson.E{Key: f.Key, Value: bson.M{mongoOp: arr}}, nil
+
+	case "between", "bw":
+		values := strings.Split(f.Value, ",")
+		if len(values) != 2 {
+			return bson.E{}, Errors.BadRequestf("The filter between on :field requires two values separated by comma", Entry{"field", f.Key})
+		}
+		gte, err := qf.filterValue(f.Key, strings.TrimSpace(values[0]))
+		if err != nil {
+			return bson.E{}, err
+		}
+		lte, err := qf.filterValue(f.Key, strings.TrimSpace(values[1]))
+		if err != nil {
+			return bson.E{}, err
+		}
+		if next, ok := nextDay(lte, strings.TrimSpace(values[1])); ok {
+			return bson.E{Key: f.Key, Value: bson.M{"$gte": gte, "$lt": next}}, nil
+		}
+		return bson.E{Key: f.Key, Value: bson.M{"$gte": gte, "$lte": lte}}, nil
+
+	case "null":
+		return bson.E{Key: f.Key, Value: nil}, nil
 
-		case "between", "bw":
-			values := strings.Split(f.Value, ",")
-			if len(values) != 2 {
-				continue // Ignorar si no hay 2 valores exactos
+	case "nnull", "notnull":
+		return bson.E{Key: f.Key, Value: bson.M{"$ne": nil}}, nil
+
+	case "lk", "like", "ilk", "ilike", "regex", "iregex":
+		// like busca el texto tal cual, para usar una expresion regular hay que pedirla con regex
+		pattern := regexp.QuoteMeta(f.Value)
+		if f.Filter == "regex" || f.Filter == "iregex" {
+			if _, er := regexp.Compile(f.Value); er != nil {
+				return bson.E{}, Errors.BadRequestf("The filter regex on :field is invalid: :error", Entry{"field", f.Key}, Entry{"error", er.Error()})
 			}
-			gte := strings.TrimSpace(values[0])
-			lte := strings.TrimSpace(values[1])
-			filters = append(filters, bson.E{Key: f.Key, Value: bson.M{"$gte": gte, "$lte": lte}})
+			pattern = f.Value
+		}
+		options := ""
+		if f.Filter == "ilk" || f.Filter == "ilike" || f.Filter == "iregex" {
+			options = "i"
+		}
+		return bson.E{Key: f.Key, Value: bson.M{"$regex": pattern, "$options": options}}, nil
+
+	default:
+		v, err := qf.filterValue(f.Key, f.Value)
+		if err != nil {
+			return bson.E{}, err
+		}
+		// lte de una fecha sin hora incluye todo ese dia
+		if next, ok := nextDay(v, f.Value); ok && mongoOp == "$lte" {
+			return bson.E{Key: f.Key, Value: bson.M{"$lt": next}}, nil
+		}
+		return bson.E{Key: f.Key, Value: bson.M{mongoOp: v}}, nil
+	}
+}
+
+// nextDay si el valor es una fecha sin hora retorna el inicio del dia siguiente en la misma zona horaria
+func nextDay(v any, value string) (time.Time, bool) {
+	date, ok := v.(time.Time)
+	if !ok || len(value) != len(time.DateOnly) {
+		return time.Time{}, false
+	}
+	return date.AddDate(0, 0, 1), true
+}
 
-		case "null":
-			filters = append(filters, bson.E{Key: f.Key, Value: nil})
+// location zona horaria del usuario, las fechas sin zona del filtro se leen en ella
+func (qf *QueryFilter) location() *time.Location {
+	if qf.Timezone == "" {
+		return time.UTC
+	}
+	loc, er := time.LoadLocation(qf.Timezone)
+	if er != nil {
+		return time.UTC
+	}
+	return loc
+}
 
-		case "nnull", "notnull":
-			filters = append(filters, bson.E{Key: f.Key, Value: bson.M{"$ne": nil}})
+// SetModel indica el modelo que se va a consultar para convertir los valores al tipo de cada campo
+func (qf *QueryFilter) SetModel(m Model) *QueryFilter {
+	if schema, ok := GetModel(m.CollectionName()); ok {
+		qf.Schema = schema
+	}
+	return qf
+}
 
-		case "lk", "like", "ilk", "ilike":
-			regex := f.Value
-			options := ""
-			if f.Filter == "ilk" || f.Filter == "ilike" {
-				options = "i"
+// filterValue convierte el valor de la url al tipo del campo en el modelo.
+// Sin modelo registrado los campos _id y *_id se toman como ObjectID y el resto como texto.
+func (qf *QueryFilter) filterValue(key string, value string) (any, Error) {
+	var t reflect.Type
+	if qf.Schema != nil {
+		if field, ok := qf.Schema.Field(key); ok {
+			t = field.Type
+			if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+				t = t.Elem()
 			}
-			filters = append(filters, bson.E{Key: f.Key, Value: bson.M{"$regex": regex, "$options": options}})
+			if t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+		}
+	}
+	if t == nil {
+		if key == "_id" || strings.HasSuffix(key, "_id") {
+			t = reflect.TypeOf(bson.ObjectID{})
+		} else {
+			return value, nil
+		}
+	}
 
-		default:
-			filters = append(filters, bson.E{Key: f.Key, Value: bson.M{mongoOp: f.Value}})
+	invalid := func(kind string) Error {
+		return Errors.BadRequestf("The value :value of :field is not a valid :type", Entry{"value", value}, Entry{"field", key}, Entry{"type", kind})
+	}
+
+	switch t {
+	case reflect.TypeOf(bson.ObjectID{}):
+		oid, er := bson.ObjectIDFromHex(value)
+		if er != nil {
+			return nil, invalid("id")
+		}
+		return oid, nil
+	case reflect.TypeOf(time.Time{}):
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", time.DateOnly} {
+			if date, er := time.ParseInLocation(layout, value, qf.location()); er == nil {
+				return date, nil
+			}
 		}
+		return nil, invalid("date")
 	}
 
-	return &filters
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, er := strconv.ParseInt(value, 10, 64)
+		if er != nil {
+			return nil, invalid("integer")
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		n, er := strconv.ParseFloat(value, 64)
+		if er != nil {
+			return nil, invalid("number")
+		}
+		return n, nil
+	case reflect.Bool:
+		b, er := strconv.ParseBool(value)
+		if er != nil {
+			return nil, invalid("boolean")
+		}
+		return b, nil
+	}
+	return value, nil
 }
@@ -0,0 +1,208 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// limites del filtro anidado para que no se pueda armar una consulta gigante
+const WHERE_MAX_LENGTH = 4096
+const WHERE_MAX_DEPTH = 5
+const WHERE_MAX_CONDITIONS = 50
+
+// FilterNode es un grupo de condiciones (and, or, not) o una condicion cuando Filter no es nil
+type FilterNode struct {
+	Logic  string
+	Filter *Filter
+	Nodes  []*FilterNode
+}
+
+// parseWhere lee el filtro anidado en json, por ejemplo:
+//
+//	?where={"and":[{"or":[{"status":"active"},{"role":{"eq":"admin"}}]},{"created_at":{"gt":"2025-01-01"}}]}
+//
+// Un objeto con varios campos es un and, un valor sin operador es eq, null es null y una lista es in.
+func parseWhere(where string, allowed []string) (*FilterNode, Error) {
+	if len(where) > WHERE_MAX_LENGTH {
+		return nil, Errors.BadRequestf("The where filter exceeds :max characters", Entry{"max", WHERE_MAX_LENGTH})
+	}
+	decoder := json.NewDecoder(bytes.NewBufferString(where))
+	decoder.UseNumber()
+	var data any
+	if er := decoder.Decode(&data); er != nil {
+		return nil, Errors.BadRequestf("The where filter is not valid json: :error", Entry{"error", er.Error()})
+	}
+
+	p := &whereParser{allowed: allowed}
+	return p.node(data, 1)
+}
+
+type whereParser struct {
+	allowed []string
+	count   int
+}
+
+func (p *whereParser) node(data any, depth int) (*FilterNode, Error) {
+	if depth > WHERE_MAX_DEPTH {
+		return nil, Errors.BadRequestf("The where filter exceeds :max levels", Entry{"max", WHERE_MAX_DEPTH})
+	}
+	object, ok := data.(map[string]any)
+	if !ok || len(object) == 0 {
+		return nil, Errors.BadRequestf("The where filter groups must be non empty objects")
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	node := &FilterNode{Logic: "and"}
+	for _, key := range keys {
+		value := object[key]
+		switch key {
+		case "and", "or":
+			list, ok := value.([]any)
+			if !ok || len(list) == 0 {
+				return nil, Errors.BadRequestf("The :logic group must be a non empty list", Entry{"logic", key})
+			}
+			group := &FilterNode{Logic: key}
+			for _, item := range list {
+				child, err := p.node(item, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				group.Nodes = append(group.Nodes, child)
+			}
+			node.Nodes = append(node.Nodes, group)
+
+		case "not":
+			child, err := p.node(value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			node.Nodes = append(node.Nodes, &FilterNode{Logic: "not", Nodes: []*FilterNode{child}})
+
+		default:
+			conditions, err := p.conditions(key, value)
+			if err != nil {
+				return nil, err
+			}
+			node.Nodes = append(node.Nodes, conditions...)
+		}
+	}
+
+	if len(node.Nodes) == 1 {
+		return node.Nodes[0], nil
+	}
+	return node, nil
+}
+
+// conditions convierte { "campo": valor } o { "campo": { "operador": valor } } en condiciones
+func (p *whereParser) conditions(field string, value any) ([]*FilterNode, Error) {
+	if !slices.Contains(p.allowed, field) {
+		return nil, Errors.BadRequestf("The field :field is not allowed to filter", Entry{"field", field})
+	}
+	key := field
+	if key == "id" {
+		key = "_id"
+	}
+
+	operators := map[string]any{}
+	switch v := value.(type) {
+	case map[string]any:
+		operators = v
+	case nil:
+		operators["null"] = ""
+	case []any:
+		operators["in"] = v
+	default:
+		operators["eq"] = v
+	}
+
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	nodes := []*FilterNode{}
+	for _, operator := range names {
+		text, err := whereValue(field, operators[operator])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOperator(operator, text, p.allowed); err != nil {
+			return nil, err
+		}
+		p.count++
+		if p.count > WHERE_MAX_CONDITIONS {
+			return nil, Errors.BadRequestf("The where filter exceeds :max conditions", Entry{"max", WHERE_MAX_CONDITIONS})
+		}
+		nodes = append(nodes, &FilterNode{Filter: &Filter{Key: key, Filter: operator, Value: text}})
+	}
+	return nodes, nil
+}
+
+// whereValue pasa el valor json a texto para reutilizar la conversion de tipos de los filtros
+func whereValue(field string, value any) (string, Error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]any); ok {
+				return "", Errors.BadRequestf("The value of :field can not be a nested list", Entry{"field", field})
+			}
+			text, err := whereValue(field, item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, text)
+		}
+		return strings.Join(values, ","), nil
+	}
+	return "", Errors.BadRequestf("The value of :field is not valid", Entry{"field", field})
+}
+
+// whereClause compila el arbol a $and, $or y $nor (el not de un grupo completo)
+func (qf *QueryFilter) whereClause(node *FilterNode) (bson.D, Error) {
+	if node.Filter != nil {
+		clause, err := qf.filterClause(*node.Filter)
+		if err != nil {
+			return nil, err
+		}
+		return bson.D{clause}, nil
+	}
+
+	children := bson.A{}
+	for _, child := range node.Nodes {
+		clause, err := qf.whereClause(child)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, clause)
+	}
+
+	switch node.Logic {
+	case "or":
+		return bson.D{{Key: "$or", Value: children}}, nil
+	case "not":
+		// $not solo aplica a un campo, para negar un grupo se usa $nor con un solo elemento
+		return bson.D{{Key: "$nor", Value: children}}, nil
+	}
+	return bson.D{{Key: "$and", Value: children}}, nil
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// nestedWhere arma levels grupos "not" anidados alrededor de una condicion
+func nestedWhere(levels int) string {
+	return strings.Repeat(`{"not":`, levels-1) + `{"status":"active"}` + strings.Repeat(`}`, levels-1)
+}
+
+// manyConditions arma un and con n condiciones sobre el mismo campo
+func manyConditions(n int) string {
+	items := make([]string, n)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"status":"s%d"}`, i)
+	}
+	return `{"and":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestParseWhere(t *testing.T) {
+	allowed := []string{"status", "role", "created_at", "id"}
+	tests := []struct {
+		name    string
+		where   string
+		allowed []string
+		ok      bool
+	}{
+		{"igualdad", `{"status":"active"}`, allowed, true},
+		{"or y not", `{"or":[{"status":"active"},{"not":{"role":{"eq":"admin"}}}]}`, allowed, true},
+		{"null y lista", `{"role":null,"status":["a","b"]}`, allowed, true},
+		{"id", `{"id":"64b7f0000000000000000000"}`, allowed, true},
+		{"json invalido", `{"status":`, allowed, false},
+		{"grupo vacio", `{}`, allowed, false},
+		{"or vacio", `{"or":[]}`, allowed, false},
+		{"campo no permitido", `{"password":"x"}`, allowed, false},
+		{"operador desconocido", `{"status":{"$where":"1"}}`, allowed, false},
+		{"lista anidada", `{"status":[["a"]]}`, allowed, false},
+		{"largo maximo", `{"status":"` + strings.Repeat("a", WHERE_MAX_LENGTH) + `"}`, allowed, false},
+		{"niveles maximos", nestedWhere(WHERE_MAX_DEPTH), allowed, true},
+		{"demasiados niveles", nestedWhere(WHERE_MAX_DEPTH + 1), allowed, false},
+		{"condiciones maximas", manyConditions(WHERE_MAX_CONDITIONS), allowed, true},
+		{"demasiadas condiciones", manyConditions(WHERE_MAX_CONDITIONS + 1), allowed, false},
+		{"regex sin permiso", `{"status":{"regex":"^a"}}`, allowed, false},
+		{"regex permitido", `{"status":{"regex":"^a"}}`, append(allowed, QUERY_REGEX), true},
+		{"regex muy largo", `{"status":{"iregex":"` + strings.Repeat("a", QUERY_MAX_REGEX_LENGTH+1) + `"}}`, append(allowed, QUERY_REGEX), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := parseWhere(tt.where, tt.allowed)
+			if tt.ok && err != nil {
+				t.Fatalf("parseWhere: %v", err)
+			}
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("parseWhere = %+v, want error", node)
+				}
+				if err.GetStatus() != 400 {
+					t.Errorf("status = %d, want 400", err.GetStatus())
+				}
+			}
+		})
+	}
+}
+
+func TestParseWhereIDKey(t *testing.T) {
+	node, err := parseWhere(`{"id":"64b7f0000000000000000000"}`, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Filter == nil || node.Filter.Key != "_id" || node.Filter.Filter != "eq" {
+		t.Errorf("node = %+v, want _id eq", node)
+	}
+}
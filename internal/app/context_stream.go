@@ -0,0 +1,180 @@
+package app
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// cada cuantas filas se hace flush de la respuesta al cliente
+const STREAM_FLUSH_ROWS = 500
+
+// CSVStream escribe un csv fila por fila directo a la respuesta.
+// Los encabezados se toman del tag json del primer registro, por eso la respuesta
+// no se envia hasta el primer Write y si no hay filas Close responde con error.
+type CSVStream struct {
+	ctx      *HttpContext
+	fileName string
+	writer   *csv.Writer
+	fields   []int
+	rows     int
+	err      Error // error del cursor o de la escritura, se reporta en Close si no alcanzo a salir ninguna fila
+}
+
+// NDJSONStream escribe un documento json por linea directo a la respuesta.
+// Igual que CSVStream la respuesta empieza en el primer Write para poder responder el error de Fail
+type NDJSONStream struct {
+	ctx      *HttpContext
+	fileName string
+	encoder  *json.Encoder
+	rows     int
+	err      Error
+}
+
+// StreamCSV inicia una respuesta csv por streaming, el separador por defecto es ';'
+func (ctx *HttpContext) StreamCSV(fileName string, comma ...rune) *CSVStream {
+	writer := csv.NewWriter(ctx.Writer)
+	writer.Comma = ';'
+	if len(comma) > 0 {
+		writer.Comma = comma[0]
+	}
+	return &CSVStream{
+		ctx:      ctx,
+		fileName: fileName,
+		writer:   writer,
+	}
+}
+
+func (s *CSVStream) Write(row any) Error {
+	val := reflect.ValueOf(row)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return Errors.InternalServerErrorf("Error writing CSV: row is not a struct")
+	}
+
+	if s.rows == 0 {
+		var headers []string
+		headers, s.fields = csvColumns(val.Type())
+		s.ctx.Writer.Header().Set("Content-Disposition", "attachment;filename="+s.fileName+".csv")
+		s.ctx.startStream("text/csv")
+		if err := s.writer.Write(headers); err != nil {
+			return Errors.InternalServerError(err)
+		}
+	}
+
+	if err := s.writer.Write(csvRecord(val, s.fields)); err != nil {
+		return Errors.InternalServerError(err)
+	}
+	s.rows++
+
+	if s.rows%STREAM_FLUSH_ROWS == 0 {
+		return s.flush()
+	}
+	return nil
+}
+
+// Fail guarda el error que detuvo el recorrido, si la respuesta no ha empezado Close lo responde
+func (s *CSVStream) Fail(err Error) {
+	s.err = err
+}
+
+// Close envia lo que quede en el buffer, si no se escribio ninguna fila responde el error de Fail o NoDocuments
+func (s *CSVStream) Close() Error {
+	if s.rows == 0 {
+		if s.err != nil {
+			s.ctx.ResponseError(s.err)
+			return nil
+		}
+		s.ctx.ResponseError(Errors.NoDocumentsf("No data available"))
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *CSVStream) flush() Error {
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return Errors.InternalServerError(err)
+	}
+	return s.ctx.flush()
+}
+
+// StreamNDJSON inicia una respuesta application/x-ndjson por streaming
+func (ctx *HttpContext) StreamNDJSON(fileName ...string) *NDJSONStream {
+	s := &NDJSONStream{
+		ctx:     ctx,
+		encoder: json.NewEncoder(ctx.Writer),
+	}
+	if len(fileName) > 0 {
+		s.fileName = fileName[0]
+	}
+	return s
+}
+
+func (s *NDJSONStream) Write(row any) Error {
+	if s.rows == 0 {
+		s.start()
+	}
+	if err := s.encoder.Encode(row); err != nil {
+		return Errors.InternalServerError(err)
+	}
+	s.rows++
+	if s.rows%STREAM_FLUSH_ROWS == 0 {
+		return s.ctx.flush()
+	}
+	return nil
+}
+
+// Fail guarda el error que detuvo el recorrido, si la respuesta no ha empezado Close lo responde
+// y si ya salieron filas se agrega el error como ultima linea para que el cliente sepa que quedo incompleta
+func (s *NDJSONStream) Fail(err Error) {
+	s.err = err
+}
+
+// Close envia lo que quede en el buffer, sin filas ni error la respuesta queda vacia
+func (s *NDJSONStream) Close() Error {
+	if s.rows == 0 {
+		if s.err != nil {
+			s.ctx.ResponseError(s.err)
+			return nil
+		}
+		s.start()
+	} else if s.err != nil {
+		s.err.Translate(s.ctx.Lang())
+		if err := s.encoder.Encode(s.err); err != nil {
+			return Errors.InternalServerError(err)
+		}
+	}
+	return s.ctx.flush()
+}
+
+func (s *NDJSONStream) start() {
+	if s.fileName != "" {
+		s.ctx.Writer.Header().Set("Content-Disposition", "attachment;filename="+s.fileName+".ndjson")
+	}
+	s.ctx.startStream("application/x-ndjson")
+}
+
+// startStream envia los encabezados y quita el WriteTimeout del servidor para esta respuesta,
+// un export grande puede tardar mas que el timeout configurado
+func (ctx *HttpContext) startStream(contentType string) {
+	ctx.Writer.Header().Set("Content-Type", contentType)
+	ctx.Writer.Header().Set("X-Content-Type-Options", "nosniff")
+	rc := http.NewResponseController(ctx.Writer)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		PrintWarning("Fail to clear write deadline for stream: :error", Entry{"error", err.Error()})
+	}
+	ctx.Writer.WriteHeader(http.StatusOK)
+}
+
+func (ctx *HttpContext) flush() Error {
+	if err := http.NewResponseController(ctx.Writer).Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return Errors.InternalServerError(err)
+	}
+	return nil
+}
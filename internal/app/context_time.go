@@ -0,0 +1,57 @@
+package app
+
+import (
+	"time"
+)
+
+// formatos de fecha que puede elegir el usuario en sus preferencias y su layout en go
+var DateFormats = map[string]string{
+	"Y-m-d": "2006-01-02",
+	"d/m/Y": "02/01/2006",
+	"m/d/Y": "01/02/2006",
+	"d.m.Y": "02.01.2006",
+	"d-m-Y": "02-01-2006",
+}
+
+const DATE_FORMAT_DEFAULT = "Y-m-d"
+
+// PreferencesInterface lo implementa el Auth que conoce las preferencias del usuario,
+// si ctx.Auth no lo implementa las fechas se responden en UTC con el formato por defecto
+type PreferencesInterface interface {
+	GetTimezone() string
+	GetDateFormat() string
+}
+
+// Location retorna la zona horaria del usuario autenticado, UTC si no tiene o no es valida
+func (ctx *HttpContext) Location() *time.Location {
+	if user, ok := ctx.Auth.(PreferencesInterface); ok && user.GetTimezone() != "" {
+		if location, er := time.LoadLocation(user.GetTimezone()); er == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// LocalTime convierte t a la zona horaria del usuario
+func (ctx *HttpContext) LocalTime(t time.Time) time.Time {
+	return t.In(ctx.Location())
+}
+
+// FormatDate formatea la fecha en la zona horaria y con el formato del usuario (ej: d/m/Y → 31/12/2025)
+func (ctx *HttpContext) FormatDate(t time.Time) string {
+	return ctx.LocalTime(t).Format(ctx.dateLayout())
+}
+
+// FormatTime igual que FormatDate con la hora (ej: 31/12/2025 18:30)
+func (ctx *HttpContext) FormatTime(t time.Time) string {
+	return ctx.LocalTime(t).Format(ctx.dateLayout() + " 15:04")
+}
+
+func (ctx *HttpContext) dateLayout() string {
+	if user, ok := ctx.Auth.(PreferencesInterface); ok {
+		if layout, ok := DateFormats[user.GetDateFormat()]; ok {
+			return layout
+		}
+	}
+	return DateFormats[DATE_FORMAT_DEFAULT]
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Encrypt cifra con AES-GCM usando una llave derivada del APP_KEY, para datos que se deben poder leer (ej: secreto TOTP).
+// Si cambia el APP_KEY lo cifrado antes ya no se puede leer
+func Encrypt(plain string) (string, Error) {
+	gcm, err := appCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, er := rand.Read(nonce); er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt descifra lo que retorno Encrypt
+func Decrypt(encrypted string) (string, Error) {
+	gcm, err := appCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, er := base64.StdEncoding.DecodeString(encrypted)
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", Errors.InternalServerErrorf("The encrypted value is invalid")
+	}
+	plain, er := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	return string(plain), nil
+}
+
+func appCipher() (cipher.AEAD, Error) {
+	key := sha256.Sum256(jwtSecret(Env.APP_KEY))
+	block, er := aes.NewCipher(key[:])
+	if er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+	gcm, er := cipher.NewGCM(block)
+	if er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+	return gcm, nil
+}
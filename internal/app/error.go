@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/x/mongo/driver"
@@ -294,12 +295,33 @@ func (e *Err) Command(err error) Error {
 	}
 }
 
+// BulkWrite los errores por documento quedan en el mapa de errores con el indice de la operacion como clave
 func (e *Err) BulkWrite(err error) Error {
-	return &Err{
+	result := &Err{
 		Status:  http.StatusBadRequest,
 		Message: "Bulk write operation error",
 		Err:     err.Error(),
 	}
+
+	var bulkEx mongo.BulkWriteException
+	if !errors.As(err, &bulkEx) || len(bulkEx.WriteErrors) == 0 {
+		return result
+	}
+
+	result.ErrMap = make(map[string][]string)
+	result.phMap = make(map[string][]List)
+	for _, we := range bulkEx.WriteErrors {
+		result.Appendf(strconv.Itoa(we.Index), bulkWriteMessage(we.Code), Entry{"error", we.Message})
+	}
+	result.Err = result.ErrMap
+	return result
+}
+
+func bulkWriteMessage(code int) string {
+	if code == 11000 {
+		return "Duplicate record: :error"
+	}
+	return "Database write error: :error"
 }
 
 func (e *Err) Driver(err error) Error {
@@ -503,6 +525,15 @@ func (e *Err) Forbiddenf(format string, ph ...Entry) Error {
 	}
 }
 
+func (e *Err) TooManyRequestsf(format string, ph ...Entry) Error {
+	return &Err{
+		Status:    http.StatusTooManyRequests,
+		Message:   "Too many requests",
+		Err:       format,
+		phMessage: ph,
+	}
+}
+
 func (e *Err) HexIDf(format string, ph ...Entry) Error {
 	return &Err{
 		Status:    http.StatusBadRequest,
@@ -0,0 +1,301 @@
+package app
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const AUTH_DRIVER_DATABASE = "database"
+const AUTH_DRIVER_JWT = "jwt"
+
+const JWT_HS256 = "HS256"
+const JWT_RS256 = "RS256"
+const JWT_EDDSA = "EdDSA"
+
+// JWTClaims son los datos que viajan firmados en el token
+type JWTClaims struct {
+	ID          string   `json:"jti"`
+	Subject     string   `json:"sub"` // id del usuario
+	Issuer      string   `json:"iss,omitempty"`
+	IssuedAt    int64    `json:"iat"`
+	ExpiresAt   int64    `json:"exp"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid"`
+}
+
+// jwtKey la llave privada solo esta en la que firma, las anteriores solo verifican
+type jwtKey struct {
+	private any
+	public  any
+}
+
+var jwtKeys struct {
+	once sync.Once
+	keys map[string]*jwtKey
+	err  Error
+}
+
+// JWTEnabled indica si la autenticacion es con tokens firmados
+func JWTEnabled() bool {
+	return Env.AUTH_DRIVER == AUTH_DRIVER_JWT
+}
+
+// JWTSign firma los claims con la llave JWT_KEY_ID, si no vienen se asignan iss e iat
+func JWTSign(claims *JWTClaims) (string, Error) {
+	keys, err := loadJWTKeys()
+	if err != nil {
+		return "", err
+	}
+	key, ok := keys[Env.JWT_KEY_ID]
+	if !ok || key.private == nil {
+		return "", Errors.InternalServerErrorf("The JWT signing key :kid does not exist", Entry{"kid", Env.JWT_KEY_ID})
+	}
+	if claims.Issuer == "" {
+		claims.Issuer = Env.APP_URL
+	}
+	if claims.IssuedAt == 0 {
+		claims.IssuedAt = time.Now().Unix()
+	}
+
+	header, er := json.Marshal(jwtHeader{Alg: Env.JWT_ALGORITHM, Typ: "JWT", Kid: Env.JWT_KEY_ID})
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	payload, er := json.Marshal(claims)
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	input := jwtEncode(header) + "." + jwtEncode(payload)
+
+	signature, err := jwtSignature(key, input)
+	if err != nil {
+		return "", err
+	}
+	return input + "." + jwtEncode(signature), nil
+}
+
+// JWTParse verifica la firma, el algoritmo, el emisor y la expiracion y retorna los claims.
+// El kid del encabezado elige la llave, asi los tokens firmados con llaves anteriores siguen siendo validos.
+func JWTParse(token string) (*JWTClaims, Error) {
+	invalid := Errors.Unauthorizedf("The token is invalid.")
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid
+	}
+	headerJSON, er := base64.RawURLEncoding.DecodeString(parts[0])
+	if er != nil {
+		return nil, invalid
+	}
+	header := jwtHeader{}
+	if er := json.Unmarshal(headerJSON, &header); er != nil {
+		return nil, invalid
+	}
+	// solo se acepta el algoritmo configurado, evita tokens con alg none o HS256 firmados con la llave publica
+	if header.Alg != Env.JWT_ALGORITHM {
+		return nil, invalid
+	}
+
+	keys, err := loadJWTKeys()
+	if err != nil {
+		return nil, err
+	}
+	key, ok := keys[header.Kid]
+	if !ok {
+		return nil, invalid
+	}
+	signature, er := base64.RawURLEncoding.DecodeString(parts[2])
+	if er != nil {
+		return nil, invalid
+	}
+	if !jwtVerify(key, parts[0]+"."+parts[1], signature) {
+		return nil, invalid
+	}
+
+	payload, er := base64.RawURLEncoding.DecodeString(parts[1])
+	if er != nil {
+		return nil, invalid
+	}
+	claims := &JWTClaims{}
+	if er := json.Unmarshal(payload, claims); er != nil {
+		return nil, invalid
+	}
+	if claims.Issuer != Env.APP_URL {
+		return nil, invalid
+	}
+	if claims.ExpiresAt <= time.Now().Unix() {
+		return nil, Errors.Unauthorizedf("Token Expired.")
+	}
+	return claims, nil
+}
+
+func jwtEncode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func jwtSignature(key *jwtKey, input string) ([]byte, Error) {
+	switch private := key.private.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, private)
+		mac.Write([]byte(input))
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		hash := sha256.Sum256([]byte(input))
+		signature, er := rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, hash[:])
+		if er != nil {
+			return nil, Errors.InternalServerError(er)
+		}
+		return signature, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(private, []byte(input)), nil
+	}
+	return nil, Errors.InternalServerErrorf("The JWT key type is not supported")
+}
+
+func jwtVerify(key *jwtKey, input string, signature []byte) bool {
+	switch public := key.public.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, public)
+		mac.Write([]byte(input))
+		return hmac.Equal(signature, mac.Sum(nil))
+	case *rsa.PublicKey:
+		hash := sha256.Sum256([]byte(input))
+		return rsa.VerifyPKCS1v15(public, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(public, []byte(input), signature)
+	}
+	return false
+}
+
+// loadJWTKeys carga las llaves una sola vez, un error de configuracion se reporta en cada peticion
+func loadJWTKeys() (map[string]*jwtKey, Error) {
+	jwtKeys.once.Do(func() {
+		switch Env.JWT_ALGORITHM {
+		case JWT_HS256:
+			jwtKeys.keys, jwtKeys.err = loadJWTSecrets()
+		case JWT_RS256, JWT_EDDSA:
+			jwtKeys.keys, jwtKeys.err = loadJWTKeyFiles(Env.JWT_KEYS_PATH)
+		default:
+			jwtKeys.err = Errors.InternalServerErrorf("The JWT algorithm :alg is not supported", Entry{"alg", Env.JWT_ALGORITHM})
+		}
+		if jwtKeys.err != nil {
+			PrintError("Fail to load JWT keys :error", E("error", jwtKeys.err.Error()))
+		}
+	})
+	return jwtKeys.keys, jwtKeys.err
+}
+
+// loadJWTSecrets la llave actual es APP_KEY, las anteriores vienen en JWT_PREVIOUS_KEYS (kid:secreto)
+func loadJWTSecrets() (map[string]*jwtKey, Error) {
+	secret := jwtSecret(Env.APP_KEY)
+	if len(secret) < 32 {
+		return nil, Errors.InternalServerErrorf("The APP_KEY must have at least 32 bytes to sign JWT")
+	}
+	keys := map[string]*jwtKey{Env.JWT_KEY_ID: {private: secret, public: secret}}
+	for _, item := range strings.Split(Env.JWT_PREVIOUS_KEYS, ",") {
+		kid, value, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || kid == "" || kid == Env.JWT_KEY_ID {
+			continue
+		}
+		keys[kid] = &jwtKey{public: jwtSecret(value)}
+	}
+	return keys, nil
+}
+
+// jwtSecret el APP_KEY puede venir como base64:...
+func jwtSecret(value string) []byte {
+	if encoded, ok := strings.CutPrefix(value, "base64:"); ok {
+		if decoded, er := base64.StdEncoding.DecodeString(encoded); er == nil {
+			return decoded
+		}
+	}
+	return []byte(value)
+}
+
+// loadJWTKeyFiles lee <kid>.key y <kid>.pub de la carpeta, el nombre del archivo es el kid.
+// Para rotar se agrega la llave nueva, se cambia JWT_KEY_ID y se deja el .pub anterior hasta que venzan sus tokens.
+func loadJWTKeyFiles(path string) (map[string]*jwtKey, Error) {
+	files, er := filepath.Glob(filepath.Join(path, "*"))
+	if er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+	keys := map[string]*jwtKey{}
+	for _, file := range files {
+		ext := filepath.Ext(file)
+		if ext != ".key" && ext != ".pub" {
+			continue
+		}
+		kid := strings.TrimSuffix(filepath.Base(file), ext)
+		data, er := os.ReadFile(file)
+		if er != nil {
+			return nil, Errors.InternalServerError(er)
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, Errors.InternalServerErrorf("The JWT key :file is not a PEM file", Entry{"file", file})
+		}
+		key, ok := keys[kid]
+		if !ok {
+			key = &jwtKey{}
+			keys[kid] = key
+		}
+
+		if ext == ".pub" {
+			public, er := x509.ParsePKIXPublicKey(block.Bytes)
+			if er != nil {
+				return nil, Errors.InternalServerError(er)
+			}
+			key.public = public
+			continue
+		}
+		private, er := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if er != nil {
+			if private, er = x509.ParsePKCS1PrivateKey(block.Bytes); er != nil {
+				return nil, Errors.InternalServerError(er)
+			}
+		}
+		switch private := private.(type) {
+		case *rsa.PrivateKey:
+			key.private, key.public = private, &private.PublicKey
+		case ed25519.PrivateKey:
+			key.private, key.public = private, private.Public()
+		default:
+			return nil, Errors.InternalServerErrorf("The JWT key :file is not RSA or Ed25519", Entry{"file", file})
+		}
+	}
+
+	for kid, key := range keys {
+		if !jwtKeyMatches(key) {
+			return nil, Errors.InternalServerErrorf("The JWT key :kid does not match :alg", Entry{"kid", kid}, Entry{"alg", Env.JWT_ALGORITHM})
+		}
+	}
+	return keys, nil
+}
+
+func jwtKeyMatches(key *jwtKey) bool {
+	switch key.public.(type) {
+	case *rsa.PublicKey:
+		return Env.JWT_ALGORITHM == JWT_RS256
+	case ed25519.PublicKey:
+		return Env.JWT_ALGORITHM == JWT_EDDSA
+	}
+	return false
+}
@@ -0,0 +1,266 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const INDEX_CREATE = "create"
+const INDEX_DROP = "drop"
+
+// IndexChange es un paso del plan para sincronizar los indices de una coleccion
+type IndexChange struct {
+	Collection string     `json:"collection"`
+	Action     string     `json:"action"` // create o drop
+	Index      ModelIndex `json:"index"`
+	Reason     string     `json:"reason"`
+}
+
+// Index declara un indice normal, order es 1 ascendente o -1 descendente
+func Index(order int, fields ...string) ModelIndex {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: order})
+	}
+	return ModelIndex{Keys: keys}
+}
+
+// UniqueIndex declara un indice unico
+func UniqueIndex(order int, fields ...string) ModelIndex {
+	index := Index(order, fields...)
+	index.Unique = true
+	return index
+}
+
+// TextIndex declara un indice de texto
+func TextIndex(fields ...string) ModelIndex {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "text"})
+	}
+	return ModelIndex{Keys: keys}
+}
+
+// GeoIndex declara un indice 2dsphere para campos GeoPoint, lo necesitan $geoNear y $near
+func GeoIndex(fields ...string) ModelIndex {
+	keys := bson.D{}
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: "2dsphere"})
+	}
+	return ModelIndex{Keys: keys}
+}
+
+// SetSparse marca el indice como sparse (solo indexa los documentos que tienen el campo)
+func (i ModelIndex) SetSparse() ModelIndex {
+	i.Sparse = true
+	return i
+}
+
+// SetTTL mongo elimina el documento cuando pasan los segundos desde la fecha del campo,
+// con 0 se elimina justo en la fecha (ej: app.Index(1, "expires_at").SetTTL(0))
+func (i ModelIndex) SetTTL(seconds int32) ModelIndex {
+	i.TTL = &seconds
+	return i
+}
+
+// SetName cambia el nombre que mongo le asigna por defecto al indice
+func (i ModelIndex) SetName(name string) ModelIndex {
+	i.Name = name
+	return i
+}
+
+// IndexName retorna el nombre del indice, si no tiene usa la convencion de mongo (ej: email_1)
+func (i ModelIndex) IndexName() string {
+	if i.Name != "" {
+		return i.Name
+	}
+	parts := []string{}
+	for _, key := range i.Keys {
+		parts = append(parts, key.Key, fmt.Sprint(key.Value))
+	}
+	return strings.Join(parts, "_")
+}
+
+// IndexModel convierte el indice declarado al modelo del driver
+func (i ModelIndex) IndexModel() mongo.IndexModel {
+	opts := options.Index().SetName(i.IndexName())
+	if i.Unique {
+		opts.SetUnique(true)
+	}
+	if i.Sparse {
+		opts.SetSparse(true)
+	}
+	if i.TTL != nil {
+		opts.SetExpireAfterSeconds(*i.TTL)
+	}
+	return mongo.IndexModel{Keys: i.Keys, Options: opts}
+}
+
+// CurrentIndexes lista los indices que tiene la coleccion en la base de datos, sin el de _id
+func CurrentIndexes(collection string) ([]ModelIndex, Error) {
+	ctx := context.TODO()
+	cursor, er := DB.Collection(collection).Indexes().List(ctx)
+	if er != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(er, &cmdErr) && cmdErr.Code == 26 { // NamespaceNotFound: la coleccion no existe
+			return []ModelIndex{}, nil
+		}
+		return nil, Errors.Mongo(er)
+	}
+	defer cursor.Close(ctx)
+
+	indexes := []ModelIndex{}
+	for cursor.Next(ctx) {
+		spec := struct {
+			Name    string `bson:"name"`
+			Key     bson.D `bson:"key"`
+			Unique  bool   `bson:"unique"`
+			Sparse  bool   `bson:"sparse"`
+			Weights bson.D `bson:"weights"`
+			TTL     *int32 `bson:"expireAfterSeconds"`
+		}{}
+		if er := cursor.Decode(&spec); er != nil {
+			return nil, Errors.Mongo(er)
+		}
+		if spec.Name == "_id_" {
+			continue
+		}
+
+		// los indices de texto se guardan como _fts/_ftsx, los campos quedan en weights
+		keys := bson.D{}
+		for _, key := range spec.Key {
+			switch key.Key {
+			case "_fts":
+				for _, w := range spec.Weights {
+					keys = append(keys, bson.E{Key: w.Key, Value: "text"})
+				}
+			case "_ftsx":
+			default:
+				keys = append(keys, bson.E{Key: key.Key, Value: indexKeyValue(key.Value)})
+			}
+		}
+		index := ModelIndex{
+			Name:   spec.Name,
+			Keys:   keys,
+			Unique: spec.Unique,
+			Sparse: spec.Sparse,
+		}
+		index.TTL = spec.TTL
+		indexes = append(indexes, index)
+	}
+	if er := cursor.Err(); er != nil {
+		return nil, Errors.Mongo(er)
+	}
+	return indexes, nil
+}
+
+// DiffIndexes compara los indices declarados en los modelos registrados con los de la base de datos.
+// Los indices que cambiaron se eliminan y se vuelven a crear.
+func DiffIndexes() ([]IndexChange, Error) {
+	plan := []IndexChange{}
+	for _, schema := range RegisteredModels() {
+		current, err := CurrentIndexes(schema.Collection)
+		if err != nil {
+			return nil, err
+		}
+
+		existing := map[string]ModelIndex{}
+		for _, index := range current {
+			existing[index.IndexName()] = index
+		}
+
+		declared := map[string]bool{}
+		for _, index := range schema.Indexes {
+			name := index.IndexName()
+			declared[name] = true
+			old, ok := existing[name]
+			if !ok {
+				plan = append(plan, IndexChange{schema.Collection, INDEX_CREATE, index, "missing"})
+				continue
+			}
+			if !sameIndex(old, index) {
+				plan = append(plan, IndexChange{schema.Collection, INDEX_DROP, old, "changed"})
+				plan = append(plan, IndexChange{schema.Collection, INDEX_CREATE, index, "changed"})
+			}
+		}
+
+		for _, index := range current {
+			if !declared[index.IndexName()] {
+				plan = append(plan, IndexChange{schema.Collection, INDEX_DROP, index, "not declared"})
+			}
+		}
+	}
+	return plan, nil
+}
+
+// ApplyIndexes ejecuta el plan, primero elimina y luego crea para no chocar con nombres repetidos
+func ApplyIndexes(plan []IndexChange) Error {
+	ctx := context.TODO()
+	for _, change := range plan {
+		if change.Action != INDEX_DROP {
+			continue
+		}
+		if er := DB.Collection(change.Collection).Indexes().DropOne(ctx, change.Index.IndexName()); er != nil {
+			return Errors.Mongo(er)
+		}
+		PrintInfo("Dropped index :collection :name", E("collection", change.Collection), E("name", change.Index.IndexName()))
+	}
+	for _, change := range plan {
+		if change.Action != INDEX_CREATE {
+			continue
+		}
+		if _, er := DB.Collection(change.Collection).Indexes().CreateOne(ctx, change.Index.IndexModel()); er != nil {
+			return Errors.Mongo(er)
+		}
+		PrintInfo("Created index :collection :name", E("collection", change.Collection), E("name", change.Index.IndexName()))
+	}
+	return nil
+}
+
+func sameIndex(a ModelIndex, b ModelIndex) bool {
+	return a.Unique == b.Unique && a.Sparse == b.Sparse && sameTTL(a.TTL, b.TTL) && indexSignature(a) == indexSignature(b)
+}
+
+func sameTTL(a *int32, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// indexSignature representa las llaves del indice, los campos de texto se ordenan
+// por que mongo no conserva el orden en que se declararon
+func indexSignature(i ModelIndex) string {
+	keys := []string{}
+	text := []string{}
+	for _, key := range i.Keys {
+		value := fmt.Sprint(indexKeyValue(key.Value))
+		if value == "text" {
+			text = append(text, key.Key)
+			continue
+		}
+		keys = append(keys, key.Key+":"+value)
+	}
+	sort.Strings(text)
+	return strings.Join(keys, ",") + "|" + strings.Join(text, ",")
+}
+
+// indexKeyValue mongo retorna el orden como int32, int64 o double segun como se creo el indice
+func indexKeyValue(v any) any {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return v
+}
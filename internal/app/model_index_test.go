@@ -0,0 +1,115 @@
+package app
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestSameIndex(t *testing.T) {
+	ttl := func(seconds int32) *int32 { return &seconds }
+	tests := []struct {
+		name     string
+		declared ModelIndex
+		current  ModelIndex // como lo retorna CurrentIndexes
+		same     bool
+	}{
+		{
+			"orden como int32",
+			Index(1, "email"),
+			ModelIndex{Name: "email_1", Keys: bson.D{{Key: "email", Value: int32(1)}}},
+			true,
+		},
+		{
+			"orden como double",
+			Index(-1, "created_at"),
+			ModelIndex{Name: "created_at_-1", Keys: bson.D{{Key: "created_at", Value: float64(-1)}}},
+			true,
+		},
+		{
+			"cambia el orden",
+			Index(1, "created_at"),
+			ModelIndex{Keys: bson.D{{Key: "created_at", Value: int32(-1)}}},
+			false,
+		},
+		{
+			"cambia el orden de los campos",
+			Index(1, "user_id", "type"),
+			ModelIndex{Keys: bson.D{{Key: "type", Value: int32(1)}, {Key: "user_id", Value: int32(1)}}},
+			false,
+		},
+		{
+			"texto en otro orden",
+			TextIndex("name", "translations.es"),
+			ModelIndex{Keys: bson.D{{Key: "translations.es", Value: "text"}, {Key: "name", Value: "text"}}},
+			true,
+		},
+		{
+			"texto con otro campo",
+			TextIndex("name"),
+			ModelIndex{Keys: bson.D{{Key: "name", Value: "text"}, {Key: "iso2", Value: "text"}}},
+			false,
+		},
+		{
+			"ahora es unico",
+			UniqueIndex(1, "email"),
+			ModelIndex{Keys: bson.D{{Key: "email", Value: int32(1)}}},
+			false,
+		},
+		{
+			"ahora es sparse",
+			Index(1, "phone").SetSparse(),
+			ModelIndex{Keys: bson.D{{Key: "phone", Value: int32(1)}}},
+			false,
+		},
+		{
+			"mismo ttl",
+			Index(1, "expires_at").SetTTL(0),
+			ModelIndex{Keys: bson.D{{Key: "expires_at", Value: int32(1)}}, TTL: ttl(0)},
+			true,
+		},
+		{
+			"ttl nuevo",
+			Index(1, "expires_at").SetTTL(0),
+			ModelIndex{Keys: bson.D{{Key: "expires_at", Value: int32(1)}}},
+			false,
+		},
+		{
+			"cambia el ttl",
+			Index(1, "expires_at").SetTTL(60),
+			ModelIndex{Keys: bson.D{{Key: "expires_at", Value: int32(1)}}, TTL: ttl(0)},
+			false,
+		},
+		{
+			"geo",
+			GeoIndex("location"),
+			ModelIndex{Keys: bson.D{{Key: "location", Value: "2dsphere"}}},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameIndex(tt.current, tt.declared); got != tt.same {
+				t.Errorf("sameIndex = %t, want %t (%s vs %s)", got, tt.same, indexSignature(tt.current), indexSignature(tt.declared))
+			}
+		})
+	}
+}
+
+func TestIndexName(t *testing.T) {
+	tests := []struct {
+		index ModelIndex
+		name  string
+	}{
+		{Index(1, "email"), "email_1"},
+		{UniqueIndex(1, "user_id", "type", "code"), "user_id_1_type_1_code_1"},
+		{Index(-1, "created_at"), "created_at_-1"},
+		{GeoIndex("location"), "location_2dsphere"},
+		{Index(1, "email").SetName("email_unique"), "email_unique"},
+	}
+	for _, tt := range tests {
+		if got := tt.index.IndexName(); got != tt.name {
+			t.Errorf("IndexName = %s, want %s", got, tt.name)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package app
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const RELATION_BELONGS_TO = "belongsTo"
+const RELATION_HAS_ONE = "hasOne"
+const RELATION_HAS_MANY = "hasMany"
+const RELATION_MANY_TO_MANY = "manyToMany"
+
+// ModelField describe un campo del modelo, los structs anidados tienen sus campos en Fields
+type ModelField struct {
+	Name   string       // nombre del campo en go
+	Bson   string       // nombre en la base de datos
+	Json   string       // nombre en la respuesta, vacio si no se serializa
+	Path   string       // ruta bson con notacion de punto (ej: profile.city_id)
+	Type   reflect.Type // tipo sin puntero
+	Fields []*ModelField
+}
+
+// ModelIndex describe un indice declarado en el modelo
+type ModelIndex struct {
+	Name   string `json:"name,omitempty"`
+	Keys   bson.D `json:"keys"`
+	Unique bool   `json:"unique,omitempty"`
+	Sparse bool   `json:"sparse,omitempty"`
+	TTL    *int32 `json:"ttl,omitempty"` // segundos despues de la fecha del campo en que mongo elimina el documento
+}
+
+// ModelRelation describe una relacion declarada en el modelo
+type ModelRelation struct {
+	Name         string // campo donde queda el resultado (as)
+	Kind         string // belongsTo, hasOne, hasMany, manyToMany
+	Collection   string // coleccion relacionada
+	LocalField   string
+	ForeignField string
+	Filter       bson.D // filtro, orden y limite por defecto sobre la coleccion relacionada
+	Sort         bson.D
+	Limit        int
+}
+
+// ModelSchema son los metadatos de un modelo registrado
+type ModelSchema struct {
+	Collection string
+	Type       reflect.Type
+	Fields     []*ModelField
+	Indexes    []ModelIndex
+	Relations  []ModelRelation
+	factory    func() Model
+}
+
+// los modelos pueden declarar sus indices y relaciones implementando estas interfaces
+type ModelIndexes interface {
+	Indexes() []ModelIndex
+}
+
+type ModelRelations interface {
+	Relations() []ModelRelation
+}
+
+var modelRegistry = map[string]*ModelSchema{}
+var modelRegistryMu sync.RWMutex
+
+// RegisterModel registra el modelo usando su constructor (ej: model.NewUser).
+// Si el modelo implementa Indexes() o Relations() tambien se guardan.
+func RegisterModel[T Model](factory func() T) *ModelSchema {
+	newModel := func() Model { return factory() }
+	m := newModel()
+
+	t := reflect.TypeOf(m)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	schema := &ModelSchema{
+		Collection: m.CollectionName(),
+		Type:       t,
+		Fields:     schemaFields(t, "", map[reflect.Type]bool{}),
+		factory:    newModel,
+	}
+	if indexes, ok := m.(ModelIndexes); ok {
+		schema.Indexes = indexes.Indexes()
+	}
+	if relations, ok := m.(ModelRelations); ok {
+		schema.Relations = relations.Relations()
+		for _, r := range schema.Relations {
+			if _, ok := schema.Field(r.Name); !ok {
+				PrintWarning("The relation {relation} of {collection} has no field to decode the result",
+					Entry{"relation", r.Name}, Entry{"collection", schema.Collection})
+			}
+		}
+	}
+
+	modelRegistryMu.Lock()
+	modelRegistry[schema.Collection] = schema
+	modelRegistryMu.Unlock()
+	return schema
+}
+
+// GetModel busca el modelo registrado por el nombre de la coleccion
+func GetModel(collection string) (*ModelSchema, bool) {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+	schema, ok := modelRegistry[collection]
+	return schema, ok
+}
+
+// RegisteredModels retorna todos los modelos registrados ordenados por coleccion
+func RegisteredModels() []*ModelSchema {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+	schemas := make([]*ModelSchema, 0, len(modelRegistry))
+	for _, schema := range modelRegistry {
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Collection < schemas[j].Collection })
+	return schemas
+}
+
+// New crea una instancia nueva del modelo lista para usar el odm
+func (s *ModelSchema) New() Model {
+	return s.factory()
+}
+
+// Field busca un campo por su ruta bson con notacion de punto (ej: profile.nickname)
+func (s *ModelSchema) Field(path string) (*ModelField, bool) {
+	fields := s.Fields
+	var found *ModelField
+	for _, name := range strings.Split(path, ".") {
+		found = nil
+		for _, f := range fields {
+			if f.Bson == name {
+				found = f
+				break
+			}
+		}
+		if found == nil {
+			return nil, false
+		}
+		fields = found.Fields
+	}
+	return found, found != nil
+}
+
+// FieldByJson busca un campo por su ruta json con notacion de punto
+func (s *ModelSchema) FieldByJson(path string) (*ModelField, bool) {
+	fields := s.Fields
+	var found *ModelField
+	for _, name := range strings.Split(path, ".") {
+		found = nil
+		for _, f := range fields {
+			if f.Json == name {
+				found = f
+				break
+			}
+		}
+		if found == nil {
+			return nil, false
+		}
+		fields = found.Fields
+	}
+	return found, found != nil
+}
+
+// Relation busca una relacion por su nombre
+func (s *ModelSchema) Relation(name string) (*ModelRelation, bool) {
+	for i := range s.Relations {
+		if s.Relations[i].Name == name {
+			return &s.Relations[i], true
+		}
+	}
+	return nil, false
+}
+
+// IsObjectID indica si el campo guarda un ObjectID o una lista de ObjectID
+func (f *ModelField) IsObjectID() bool {
+	t := f.Type
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == reflect.TypeOf(bson.ObjectID{})
+}
+
+func schemaFields(t reflect.Type, prefix string, visited map[reflect.Type]bool) []*ModelField {
+	visited[t] = true
+	defer delete(visited, t)
+
+	fields := []*ModelField{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() || sf.Anonymous {
+			continue
+		}
+		name := bsonFieldName(sf)
+		if name == "-" {
+			continue
+		}
+
+		jsonName := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			jsonName = ""
+		} else if jsonName == "" {
+			jsonName = sf.Name
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		field := &ModelField{
+			Name: sf.Name,
+			Bson: name,
+			Json: jsonName,
+			Path: prefix + name,
+			Type: ft,
+		}
+
+		// los structs anidados (no relaciones) se describen campo por campo
+		if ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}) && !visited[ft] && !isModelType(ft) {
+			field.Fields = schemaFields(ft, field.Path+".", visited)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func isModelType(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(reflect.TypeOf((*Model)(nil)).Elem())
+}
@@ -0,0 +1,230 @@
+package app
+
+import (
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// constructores de relaciones para declarar en Relations() del modelo,
+// name es el campo bson del struct donde se decodifica el resultado
+
+// BelongsTo el documento guarda el id del padre en localField (ej: access_tokens.user_id → users)
+func BelongsTo(name string, collection string, localField string) ModelRelation {
+	return ModelRelation{Name: name, Kind: RELATION_BELONGS_TO, Collection: collection, LocalField: localField, ForeignField: "_id"}
+}
+
+// HasOne el hijo guarda el id de este documento en foreignField, se decodifica en un puntero
+func HasOne(name string, collection string, foreignField string) ModelRelation {
+	return ModelRelation{Name: name, Kind: RELATION_HAS_ONE, Collection: collection, LocalField: "_id", ForeignField: foreignField}
+}
+
+// HasMany los hijos guardan el id de este documento en foreignField (ej: users → access_tokens.user_id)
+func HasMany(name string, collection string, foreignField string) ModelRelation {
+	return ModelRelation{Name: name, Kind: RELATION_HAS_MANY, Collection: collection, LocalField: "_id", ForeignField: foreignField}
+}
+
+// ManyToMany el documento guarda la lista de ids relacionados en localField (ej: users.role_ids → roles)
+func ManyToMany(name string, collection string, localField string) ModelRelation {
+	return ModelRelation{Name: name, Kind: RELATION_MANY_TO_MANY, Collection: collection, LocalField: localField, ForeignField: "_id"}
+}
+
+// filtro, orden y limite que siempre se aplican al cargar la relacion
+func (r ModelRelation) SetFilter(filter bson.D) ModelRelation {
+	r.Filter = filter
+	return r
+}
+
+func (r ModelRelation) SetSort(sort bson.D) ModelRelation {
+	r.Sort = sort
+	return r
+}
+
+func (r ModelRelation) SetLimit(limit int) ModelRelation {
+	r.Limit = limit
+	return r
+}
+
+// RelationQuery es una relacion a cargar con WithRelations, el filtro, orden y limite
+// se aplican sobre la coleccion relacionada ademas de los declarados en el modelo
+type RelationQuery struct {
+	Name   string // ruta de la relacion, las anidadas con punto (ej: roles.permissions)
+	Filter bson.D
+	Sort   bson.D
+	Limit  int
+}
+
+func Relation(name string) *RelationQuery {
+	return &RelationQuery{Name: name}
+}
+
+func (q *RelationQuery) SetFilter(filter bson.D) *RelationQuery {
+	q.Filter = filter
+	return q
+}
+
+func (q *RelationQuery) SetSort(sort bson.D) *RelationQuery {
+	q.Sort = sort
+	return q
+}
+
+func (q *RelationQuery) SetLimit(limit int) *RelationQuery {
+	q.Limit = limit
+	return q
+}
+
+// With arma los $lookup de las relaciones declaradas en el modelo,
+// user.With("roles.permissions", "access_tokens") carga los roles con sus permisos y los tokens
+func (o *Odm) With(relations ...string) ([]bson.D, Error) {
+	queries := make([]*RelationQuery, len(relations))
+	for i, name := range relations {
+		queries[i] = Relation(name)
+	}
+	return o.WithRelations(queries...)
+}
+
+// WithRelations igual que With pero con filtros, orden y limite por relacion:
+// user.WithRelations(app.Relation("access_tokens").SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(5))
+func (o *Odm) WithRelations(queries ...*RelationQuery) ([]bson.D, Error) {
+	tree := []*relationNode{}
+	for _, query := range queries {
+		tree = addRelationNode(tree, strings.Split(query.Name, "."), query)
+	}
+	return relationLookups(o.Model.CollectionName(), tree)
+}
+
+// FindOneWith busca un documento y carga las relaciones en el modelo
+func (o *Odm) FindOneWith(filter bson.D, relations ...string) Error {
+	lookups, err := o.With(relations...)
+	if err != nil {
+		return err
+	}
+	if filter == nil {
+		filter = bson.D{}
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	pipeline = append(pipeline, lookups...)
+	return o.AggregateOne(pipeline)
+}
+
+// FindWith busca los documentos y carga las relaciones, result debe ser un puntero a un slice
+func (o *Odm) FindWith(result any, filter bson.D, relations ...string) Error {
+	lookups, err := o.With(relations...)
+	if err != nil {
+		return err
+	}
+	if filter == nil {
+		filter = bson.D{}
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: filter}}}
+	pipeline = append(pipeline, lookups...)
+	return o.Aggregate(result, pipeline)
+}
+
+// relationNode agrupa las rutas por relacion para que roles y roles.permissions sean un solo $lookup
+type relationNode struct {
+	name     string
+	query    *RelationQuery
+	children []*relationNode
+}
+
+func addRelationNode(nodes []*relationNode, path []string, query *RelationQuery) []*relationNode {
+	var node *relationNode
+	for _, n := range nodes {
+		if n.name == path[0] {
+			node = n
+			break
+		}
+	}
+	if node == nil {
+		node = &relationNode{name: path[0]}
+		nodes = append(nodes, node)
+	}
+	if len(path) == 1 {
+		node.query = query
+	} else {
+		node.children = addRelationNode(node.children, path[1:], query)
+	}
+	return nodes
+}
+
+func relationLookups(collection string, nodes []*relationNode) ([]bson.D, Error) {
+	if len(nodes) == 0 {
+		return []bson.D{}, nil
+	}
+	schema, ok := GetModel(collection)
+	if !ok {
+		return nil, Errors.InternalServerErrorf("The model :collection is not registered", Entry{"collection", collection})
+	}
+
+	stages := []bson.D{}
+	for _, node := range nodes {
+		relation, ok := schema.Relation(node.name)
+		if !ok {
+			return nil, Errors.BadRequestf("The relation :relation does not exist in :collection",
+				Entry{"relation", node.name}, Entry{"collection", collection})
+		}
+		nested, err := relationLookups(relation.Collection, node.children)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, relation.lookup(node.query, nested)...)
+	}
+	return stages, nil
+}
+
+// lookup arma el $lookup con let/pipeline para poder filtrar, ordenar y anidar relaciones,
+// belongsTo y hasOne se desenvuelven para decodificar en un puntero en vez de una lista
+func (r *ModelRelation) lookup(query *RelationQuery, nested []bson.D) []bson.D {
+	var match bson.D
+	if r.Kind == RELATION_MANY_TO_MANY {
+		match = bson.D{{Key: "$in", Value: bson.A{"$" + r.ForeignField, bson.D{{Key: "$ifNull", Value: bson.A{"$$local", bson.A{}}}}}}}
+	} else {
+		match = bson.D{{Key: "$eq", Value: bson.A{"$" + r.ForeignField, "$$local"}}}
+	}
+
+	pipeline := bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "$expr", Value: match}}}}}
+	if len(r.Filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: r.Filter}})
+	}
+	sort, limit := r.Sort, r.Limit
+	if query != nil {
+		if len(query.Filter) > 0 {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: query.Filter}})
+		}
+		if len(query.Sort) > 0 {
+			sort = query.Sort
+		}
+		if query.Limit > 0 {
+			limit = query.Limit
+		}
+	}
+	if len(sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	}
+	single := r.Kind == RELATION_BELONGS_TO || r.Kind == RELATION_HAS_ONE
+	if single {
+		limit = 1
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+	for _, stage := range nested {
+		pipeline = append(pipeline, stage)
+	}
+
+	stages := []bson.D{{{Key: "$lookup", Value: bson.D{
+		{Key: "from", Value: r.Collection},
+		{Key: "let", Value: bson.D{{Key: "local", Value: "$" + r.LocalField}}},
+		{Key: "pipeline", Value: pipeline},
+		{Key: "as", Value: r.Name},
+	}}}}
+	if single {
+		stages = append(stages, bson.D{{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$" + r.Name},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}}})
+	}
+	return stages
+}
@@ -23,7 +23,8 @@ type Model interface {
 type Collection []Model
 
 type Odm struct {
-	Model Model `bson:"-" json:"-"`
+	Model     Model `bson:"-" json:"-"`
+	unchanged bool
 }
 
 var DBClient *mongo.Client
@@ -90,9 +91,9 @@ func (o *Odm) FindBy(result any, field string, value any, opts ...options.Lister
 	return nil
 }
 
-func (o *Odm) Aggregate(result any, pipeline mongo.Pipeline) Error {
+func (o *Odm) Aggregate(result any, pipeline mongo.Pipeline, opts ...options.Lister[options.AggregateOptions]) Error {
 	ctx := context.TODO()
-	cursor, err := DB.Collection(o.Model.CollectionName()).Aggregate(ctx, pipeline)
+	cursor, err := DB.Collection(o.Model.CollectionName()).Aggregate(ctx, pipeline, opts...)
 	if err != nil {
 		return Errors.Mongo(err)
 	}
@@ -165,6 +166,7 @@ func (o *Odm) CreateMany(data any) Error {
 }
 
 func (o *Odm) Update() Error {
+	o.unchanged = false
 	if err := o.Model.BeforeUpdate(); err != nil {
 		return err
 	}
@@ -178,18 +180,72 @@ func (o *Odm) Update() Error {
 	if result.MatchedCount == 0 {
 		return Errors.NoDocumentsf("mongo.UpdateResult.MatchedCount == 0")
 	}
-	if result.ModifiedCount == 0 {
-		return Errors.Updatef("mongo.UpdateResult.ModifiedCount == 0")
+	o.unchanged = result.ModifiedCount == 0
+	return nil
+}
+
+// UpdateFields actualiza solo los campos del mapa dirty (claves bson, admite notacion de punto).
+// Los valores nil se eliminan con $unset. Ejecuta el hook BeforeUpdate y agrega los campos
+// que el hook modifique (ej: updated_at). Si no hay cambios no toca la base de datos.
+func (o *Odm) UpdateFields(dirty map[string]any) Error {
+	o.unchanged = false
+	if len(dirty) == 0 {
+		o.unchanged = true
+		return nil
+	}
+
+	changes, err := updateChanges(o.Model, dirty)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.D{bson.E{Key: "_id", Value: o.Model.GetID()}}
+	result, er := DB.Collection(o.Model.CollectionName()).UpdateOne(context.TODO(), filter, UpdateDocument(changes))
+	if er != nil {
+		return Errors.Mongo(er)
+	}
+	if result.MatchedCount == 0 {
+		return Errors.NoDocumentsf("mongo.UpdateResult.MatchedCount == 0")
 	}
+	o.unchanged = result.ModifiedCount == 0
 	return nil
 }
 
+// updateChanges ejecuta BeforeUpdate y retorna dirty mas los campos que el hook modifico
+func updateChanges(m Model, dirty map[string]any) (map[string]any, Error) {
+	before := bsonFields(m)
+	if err := m.BeforeUpdate(); err != nil {
+		return nil, err
+	}
+	changes := make(map[string]any, len(dirty))
+	for key, value := range dirty {
+		changes[key] = value
+	}
+	for key, value := range bsonFields(m) {
+		if _, ok := changes[key]; ok {
+			continue
+		}
+		if !reflect.DeepEqual(before[key], value) {
+			changes[key] = value
+		}
+	}
+	return changes, nil
+}
+
+// UpdateBy llena el modelo con el validator y actualiza solo los campos que cambiaron.
+// Si el validator no trae cambios no se ejecuta la actualizacion y Unchanged() retorna true.
 func (o *Odm) UpdateBy(validator any) (map[string]any, map[string]any, Error) {
 	original, dirty, err := Fill(o.Model, validator)
 	if err != nil {
 		return original, dirty, err
 	}
-	return original, dirty, o.Update()
+	return original, dirty, o.UpdateFields(dirty)
+}
+
+// Unchanged indica si la ultima actualizacion no modifico el documento.
+// No es un error: el documento existe pero ya tenia esos valores.
+func (o *Odm) Unchanged() bool {
+	return o.unchanged
 }
 
 // OjO no usa el hook BeforeUpdate
@@ -197,6 +253,7 @@ func (o *Odm) UpdateOne(filter bson.D, update bson.D) Error {
 	// if err := o.Model.BeforeUpdate(); err != nil {
 	// 	return err
 	// }
+	o.unchanged = false
 	result, err := DB.Collection(o.Model.CollectionName()).UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return Errors.Mongo(err)
@@ -204,9 +261,7 @@ func (o *Odm) UpdateOne(filter bson.D, update bson.D) Error {
 	if result.MatchedCount == 0 {
 		return Errors.NoDocumentsf("mongo.UpdateResult.MatchedCount == 0")
 	}
-	if result.ModifiedCount == 0 {
-		return Errors.Updatef("mongo.UpdateResult.ModifiedCount == 0")
-	}
+	o.unchanged = result.ModifiedCount == 0
 	return nil
 }
 
@@ -0,0 +1,287 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const BULK_INSERT = 1
+const BULK_UPDATE = 2
+const BULK_UPSERT = 3
+const BULK_DELETE = 4
+
+const BULK_DEFAULT_BATCH_SIZE = 1000
+
+// BulkOperation es una operacion dentro de un BulkWrite.
+// Si Model no es nil se invocan sus hooks y se usa para armar el documento.
+type BulkOperation struct {
+	Action int
+	Model  Model
+	Filter bson.D
+	Update bson.D
+	Dirty  map[string]any // campos que cambiaron en BulkUpdate (ver Fill)
+}
+
+type BulkOptions struct {
+	Ordered   bool // si es true se detiene en el primer error
+	BatchSize int  // cantidad de operaciones por lote (predeterminado: 1000)
+}
+
+type BulkResult struct {
+	Inserted  int64 `json:"inserted"`
+	Matched   int64 `json:"matched"`
+	Modified  int64 `json:"modified"`
+	Upserted  int64 `json:"upserted"`
+	Deleted   int64 `json:"deleted"`
+	Processed int   `json:"processed"` // operaciones procesadas, en modo ordenado es el indice desde donde reanudar
+}
+
+func NewBulkOptions() *BulkOptions {
+	return &BulkOptions{
+		Ordered:   true,
+		BatchSize: BULK_DEFAULT_BATCH_SIZE,
+	}
+}
+
+// BulkInsert inserta el modelo, ejecuta BeforeCreate y le asigna el _id antes de enviarlo
+func BulkInsert(m Model) BulkOperation {
+	return BulkOperation{Action: BULK_INSERT, Model: m}
+}
+
+// BulkUpdate actualiza el modelo por _id solo con los campos de dirty, igual que UpdateFields ejecuta BeforeUpdate
+// y agrega lo que el hook cambie. Si no hay cambios la operacion no se envia
+func BulkUpdate(m Model, dirty map[string]any) BulkOperation {
+	return BulkOperation{Action: BULK_UPDATE, Model: m, Dirty: dirty}
+}
+
+// BulkUpdateOne actualiza el primer documento que coincida con el filtro, OjO no usa hooks
+func BulkUpdateOne(filter bson.D, update bson.D) BulkOperation {
+	return BulkOperation{Action: BULK_UPDATE, Filter: filter, Update: update}
+}
+
+// BulkUpsert actualiza el documento que coincida con el filtro o lo crea si no existe.
+// Los campos que solo modifica BeforeCreate (ej: created_at) se envian con $setOnInsert.
+func BulkUpsert(filter bson.D, m Model) BulkOperation {
+	return BulkOperation{Action: BULK_UPSERT, Model: m, Filter: filter}
+}
+
+// BulkDelete elimina el modelo por _id
+func BulkDelete(m Model) BulkOperation {
+	return BulkOperation{Action: BULK_DELETE, Model: m}
+}
+
+// BulkDeleteOne elimina el primer documento que coincida con el filtro
+func BulkDeleteOne(filter bson.D) BulkOperation {
+	return BulkOperation{Action: BULK_DELETE, Filter: filter}
+}
+
+// BulkWrite ejecuta operaciones mixtas (insert, update, upsert, delete) por lotes.
+// Los errores por documento se retornan en el mapa del error con el indice de la operacion como clave.
+// En modo ordenado se detiene en el primer error y BulkResult.Processed indica desde donde reanudar.
+func (o *Odm) BulkWrite(operations []BulkOperation, opts ...*BulkOptions) (*BulkResult, Error) {
+	opt := NewBulkOptions()
+	if len(opts) > 0 && opts[0] != nil {
+		opt = opts[0]
+	}
+	batchSize := opt.BatchSize
+	if batchSize <= 0 {
+		batchSize = BULK_DEFAULT_BATCH_SIZE
+	}
+
+	ctx := context.TODO()
+	collection := DB.Collection(o.Model.CollectionName())
+	result := &BulkResult{}
+	bulkErr := &Err{
+		Status:  http.StatusBadRequest,
+		Message: "Bulk write operation error",
+		ErrMap:  make(map[string][]string),
+		phMap:   make(map[string][]List),
+	}
+
+	for offset := 0; offset < len(operations); offset += batchSize {
+		end := offset + batchSize
+		if end > len(operations) {
+			end = len(operations)
+		}
+
+		writeModels := []mongo.WriteModel{}
+		indexes := []int{} // indice de la operacion original por cada write model
+		for i := offset; i < end; i++ {
+			writeModel, err := operations[i].writeModel()
+			if err != nil {
+				bulkErr.Appendf(strconv.Itoa(i), err.GetMessage()+": :error", Entry{"error", err.GetErr()})
+				if opt.Ordered {
+					break
+				}
+				continue
+			}
+			if writeModel == nil {
+				continue // BulkUpdate sin cambios
+			}
+			writeModels = append(writeModels, writeModel)
+			indexes = append(indexes, i)
+		}
+
+		if len(writeModels) > 0 {
+			res, er := collection.BulkWrite(ctx, writeModels, options.BulkWrite().SetOrdered(opt.Ordered))
+			if res != nil {
+				result.Inserted += res.InsertedCount
+				result.Matched += res.MatchedCount
+				result.Modified += res.ModifiedCount
+				result.Upserted += res.UpsertedCount
+				result.Deleted += res.DeletedCount
+				for i, id := range res.UpsertedIDs {
+					if oid, ok := id.(bson.ObjectID); ok && operations[indexes[i]].Model != nil {
+						operations[indexes[i]].Model.SetID(oid)
+					}
+				}
+			}
+			if er != nil {
+				var bulkEx mongo.BulkWriteException
+				if !errors.As(er, &bulkEx) || len(bulkEx.WriteErrors) == 0 {
+					return result, Errors.Mongo(er)
+				}
+				for _, we := range bulkEx.WriteErrors {
+					index := indexes[we.Index]
+					bulkErr.Appendf(strconv.Itoa(index), bulkWriteMessage(we.Code), Entry{"error", we.Message})
+					if opt.Ordered {
+						result.Processed = index
+						return result, bulkErr.bulkWriteError()
+					}
+				}
+			}
+		}
+
+		if opt.Ordered && len(bulkErr.ErrMap) > 0 {
+			// fallo un hook, todo lo anterior a esa operacion ya se envio
+			result.Processed = offset + len(writeModels)
+			return result, bulkErr.bulkWriteError()
+		}
+		result.Processed = end
+	}
+
+	return result, bulkErr.bulkWriteError()
+}
+
+// writeModel invoca los hooks del modelo y arma el write model de mongo
+func (op *BulkOperation) writeModel() (mongo.WriteModel, Error) {
+	switch op.Action {
+	case BULK_INSERT:
+		if op.Model == nil {
+			return nil, Errors.BulkWritef("insert operation requires a model")
+		}
+		if err := op.Model.BeforeCreate(); err != nil {
+			return nil, err
+		}
+		// el _id se asigna aca por que BulkWriteResult no retorna los ids insertados
+		if op.Model.GetID().IsZero() {
+			op.Model.SetID(bson.NewObjectID())
+		}
+		return mongo.NewInsertOneModel().SetDocument(op.Model), nil
+
+	case BULK_UPDATE:
+		if op.Model == nil {
+			return mongo.NewUpdateOneModel().SetFilter(op.Filter).SetUpdate(op.Update), nil
+		}
+		if len(op.Dirty) == 0 {
+			return nil, nil
+		}
+		changes, err := updateChanges(op.Model, op.Dirty)
+		if err != nil {
+			return nil, err
+		}
+		filter := op.Filter
+		if filter == nil {
+			filter = bson.D{bson.E{Key: "_id", Value: op.Model.GetID()}}
+		}
+		return mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(UpdateDocument(changes)), nil
+
+	case BULK_UPSERT:
+		if op.Model == nil {
+			return mongo.NewUpdateOneModel().SetFilter(op.Filter).SetUpdate(op.Update).SetUpsert(true), nil
+		}
+		update, err := upsertDocument(op.Model)
+		if err != nil {
+			return nil, err
+		}
+		return mongo.NewUpdateOneModel().SetFilter(op.Filter).SetUpdate(update).SetUpsert(true), nil
+
+	case BULK_DELETE:
+		filter := op.Filter
+		if filter == nil && op.Model != nil {
+			filter = bson.D{bson.E{Key: "_id", Value: op.Model.GetID()}}
+		}
+		if filter == nil {
+			return nil, Errors.BulkWritef("delete operation requires a model or a filter")
+		}
+		return mongo.NewDeleteOneModel().SetFilter(filter), nil
+	}
+
+	return nil, Errors.BulkWritef("unknown bulk operation :action", Entry{"action", op.Action})
+}
+
+// upsertDocument ejecuta BeforeUpdate y BeforeCreate sobre el modelo,
+// los campos que solo cambia BeforeCreate van en $setOnInsert y el resto en $set
+func upsertDocument(m Model) (bson.D, Error) {
+	original := bsonFields(m)
+	if err := m.BeforeUpdate(); err != nil {
+		return nil, err
+	}
+	updated := bsonFields(m)
+	if err := m.BeforeCreate(); err != nil {
+		return nil, err
+	}
+	created := bsonFields(m)
+
+	onInsert := map[string]bool{}
+	for key, value := range created {
+		if !reflect.DeepEqual(updated[key], value) && reflect.DeepEqual(original[key], updated[key]) {
+			onInsert[key] = true
+		}
+	}
+
+	data, er := bson.Marshal(m)
+	if er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+	document := bson.D{}
+	if er := bson.Unmarshal(data, &document); er != nil {
+		return nil, Errors.InternalServerError(er)
+	}
+
+	set := bson.D{}
+	setOnInsert := bson.D{}
+	for _, e := range document {
+		if e.Key == "_id" || onInsert[e.Key] {
+			setOnInsert = append(setOnInsert, e)
+		} else {
+			set = append(set, e)
+		}
+	}
+
+	update := bson.D{}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(setOnInsert) > 0 {
+		update = append(update, bson.E{Key: "$setOnInsert", Value: setOnInsert})
+	}
+	return update, nil
+}
+
+func (e *Err) bulkWriteError() Error {
+	if len(e.ErrMap) == 0 {
+		return nil
+	}
+	e.Err = e.ErrMap
+	return e
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"iter"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// cantidad de documentos que trae mongo por cada viaje si no se especifica otra
+const CURSOR_BATCH_SIZE = 500
+
+// Cursor envuelve un mongo.Cursor para recorrer resultados grandes sin cargarlos todos en memoria
+type Cursor struct {
+	cursor *mongo.Cursor
+	ctx    context.Context
+}
+
+// FindCursor igual que Find pero retorna un cursor para recorrer documento por documento.
+// El tamaño del lote se controla con options.Find().SetBatchSize().
+func (o *Odm) FindCursor(filter bson.D, opts ...options.Lister[options.FindOptions]) (*Cursor, Error) {
+	ctx := context.TODO()
+	opts = append([]options.Lister[options.FindOptions]{options.Find().SetBatchSize(CURSOR_BATCH_SIZE)}, opts...)
+	cursor, err := DB.Collection(o.Model.CollectionName()).Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, Errors.Mongo(err)
+	}
+	return &Cursor{cursor: cursor, ctx: ctx}, nil
+}
+
+// AggregateCursor igual que Aggregate pero retorna un cursor para recorrer documento por documento.
+// El tamaño del lote se controla con options.Aggregate().SetBatchSize().
+func (o *Odm) AggregateCursor(pipeline mongo.Pipeline, opts ...options.Lister[options.AggregateOptions]) (*Cursor, Error) {
+	ctx := context.TODO()
+	opts = append([]options.Lister[options.AggregateOptions]{options.Aggregate().SetBatchSize(CURSOR_BATCH_SIZE)}, opts...)
+	cursor, err := DB.Collection(o.Model.CollectionName()).Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return nil, Errors.Mongo(err)
+	}
+	return &Cursor{cursor: cursor, ctx: ctx}, nil
+}
+
+func (c *Cursor) Close() Error {
+	if err := c.cursor.Close(c.ctx); err != nil {
+		return Errors.Mongo(err)
+	}
+	return nil
+}
+
+// Each decodifica cada documento del cursor en un nuevo T y llama a fn.
+// Se detiene en el primer error de fn o de mongo y siempre cierra el cursor.
+func Each[T any](c *Cursor, fn func(doc *T) Error) Error {
+	for doc, err := range Rows[T](c) {
+		if err != nil {
+			return err
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rows retorna un iterador sobre el cursor para usar con range:
+//
+//	for user, err := range app.Rows[model.User](cursor) { ... }
+//
+// Si hay un error se entrega como ultimo elemento. El cursor se cierra al terminar o al hacer break.
+func Rows[T any](c *Cursor) iter.Seq2[*T, Error] {
+	return func(yield func(*T, Error) bool) {
+		defer c.cursor.Close(c.ctx)
+
+		for c.cursor.Next(c.ctx) {
+			doc := new(T)
+			if err := c.cursor.Decode(doc); err != nil {
+				yield(nil, Errors.Mongo(err))
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+		if err := c.cursor.Err(); err != nil {
+			yield(nil, Errors.Mongo(err))
+		}
+	}
+}
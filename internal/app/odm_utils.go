@@ -3,6 +3,7 @@ package app
 import (
 	"context"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
@@ -45,8 +46,8 @@ func CloseMongoDB() error {
 	return DBClient.Disconnect(context.TODO())
 }
 
-// FillChanges compara model con request y retorna los valores antiguos y los nuevos.
-// Usa el tag bson como clave.
+// Fill compara model con request y retorna los valores antiguos y los nuevos.
+// Usa el tag bson como clave, los campos de structs anidados usan notacion de punto (ej: profile.nickname).
 // Además actualiza el model con los valores nuevos.
 // @return original, dirty, error
 func Fill(model any, request any) (map[string]any, map[string]any, Error) {
@@ -68,29 +69,28 @@ func Fill(model any, request any) (map[string]any, map[string]any, Error) {
 		return original, dirty, Errors.Unknownf("The parameters model and request must be structs")
 	}
 
-	modelType := modelValue.Type()
 	requestType := requestValue.Type()
 
 	for i := 0; i < requestType.NumField(); i++ {
 		requestField := requestType.Field(i)
-		fieldName := requestField.Name
 		requestFieldValue := requestValue.Field(i)
 
 		if !requestField.IsExported() {
 			continue
 		}
 
-		modelField := modelValue.FieldByName(fieldName)
-		if !modelField.IsValid() || !modelField.CanSet() {
+		if requestFieldValue.IsZero() {
 			continue
 		}
 
-		if requestFieldValue.IsZero() {
+		index, path, found := fieldPath(modelValue.Type(), requestField.Name)
+		if !found {
 			continue
 		}
+		fieldType := modelValue.Type().FieldByIndex(index).Type
 
 		var newValue reflect.Value
-		if modelField.Type() == reflect.TypeOf(bson.ObjectID{}) &&
+		if fieldType == reflect.TypeOf(bson.ObjectID{}) &&
 			requestFieldValue.Kind() == reflect.String {
 
 			oid, err := bson.ObjectIDFromHex(requestFieldValue.String())
@@ -99,32 +99,44 @@ func Fill(model any, request any) (map[string]any, map[string]any, Error) {
 			}
 			newValue = reflect.ValueOf(oid)
 
-		} else if requestFieldValue.Type().AssignableTo(modelField.Type()) {
+		} else if requestFieldValue.Type().AssignableTo(fieldType) {
 			newValue = requestFieldValue
-		} else if requestFieldValue.Type().ConvertibleTo(modelField.Type()) {
-			newValue = requestFieldValue.Convert(modelField.Type())
+		} else if requestFieldValue.Type().ConvertibleTo(fieldType) {
+			newValue = requestFieldValue.Convert(fieldType)
 		} else {
 			continue
 		}
 
-		currentValue := modelField
-		if !reflect.DeepEqual(currentValue.Interface(), newValue.Interface()) {
-			// sacar el tag bson
-			modelFieldStruct, found := modelType.FieldByName(fieldName)
-			if !found {
-				continue
+		// se recorre el camino hasta el campo, si hay un puntero nil en medio
+		// se crea y se marca completo como sucio por que en mongo no se puede
+		// hacer $set de "profile.nickname" cuando profile es null
+		modelField := modelValue
+		for depth, idx := range index {
+			modelField = modelField.Field(idx)
+			if depth == len(index)-1 {
+				break
 			}
-
-			bsonTag := modelFieldStruct.Tag.Get("bson")
-			if bsonTag == "" {
-				bsonTag = strings.ToLower(fieldName)
-			} else if commaIndex := strings.Index(bsonTag, ","); commaIndex != -1 {
-				bsonTag = bsonTag[:commaIndex]
+			if modelField.Kind() == reflect.Ptr {
+				if modelField.IsNil() {
+					modelField.Set(reflect.New(modelField.Type().Elem()))
+					parent := strings.Join(path[:depth+1], ".")
+					original[parent] = nil
+					dirty[parent] = modelField.Interface()
+				}
+				modelField = modelField.Elem()
 			}
+		}
+
+		if !modelField.CanSet() {
+			continue
+		}
+
+		if !reflect.DeepEqual(modelField.Interface(), newValue.Interface()) {
+			key := strings.Join(path, ".")
 
 			// Guardamos viejo y nuevo
-			original[bsonTag] = currentValue.Interface()
-			dirty[bsonTag] = newValue.Interface()
+			original[key] = modelField.Interface()
+			dirty[key] = newValue.Interface()
 
 			// Actualizamos el modelo
 			modelField.Set(newValue)
@@ -134,6 +146,125 @@ func Fill(model any, request any) (map[string]any, map[string]any, Error) {
 	return original, dirty, nil
 }
 
+// UpdateDocument arma un documento de actualizacion minimo a partir de un mapa dirty.
+// Los valores nil van a $unset y el resto a $set, las claves admiten notacion de punto.
+// Si una clave y su padre estan presentes (ej: profile y profile.nickname) se usa solo el padre.
+func UpdateDocument(dirty map[string]any) bson.D {
+	keys := make([]string, 0, len(dirty))
+	for key := range dirty {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	set := bson.D{}
+	unset := bson.D{}
+	for _, key := range keys {
+		if hasParentPath(dirty, key) {
+			continue
+		}
+		value := dirty[key]
+		if isNilValue(value) {
+			unset = append(unset, bson.E{Key: key, Value: ""})
+		} else {
+			set = append(set, bson.E{Key: key, Value: value})
+		}
+	}
+
+	update := bson.D{}
+	if len(set) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: set})
+	}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	return update
+}
+
+// fieldPath busca un campo por nombre en el tipo y en sus structs anidados.
+// Primero revisa los campos directos y luego baja a los structs anidados.
+// @return el indice para FieldByIndex, la ruta bson y si lo encontro
+func fieldPath(t reflect.Type, name string) ([]int, []string, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && !field.Anonymous && field.Name == name {
+			return []int{i}, []string{bsonFieldName(field)}, true
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous || bsonFieldName(field) == "-" {
+			continue
+		}
+		nested := field.Type
+		if nested.Kind() == reflect.Ptr {
+			nested = nested.Elem()
+		}
+		if nested.Kind() != reflect.Struct || nested == reflect.TypeOf(time.Time{}) {
+			continue
+		}
+		if index, path, found := fieldPath(nested, name); found {
+			return append([]int{i}, index...), append([]string{bsonFieldName(field)}, path...), true
+		}
+	}
+
+	return nil, nil, false
+}
+
+// bsonFieldName retorna el nombre del campo segun el tag bson sin opciones como ",omitempty"
+func bsonFieldName(field reflect.StructField) string {
+	bsonTag := field.Tag.Get("bson")
+	if bsonTag == "" {
+		return strings.ToLower(field.Name)
+	}
+	if commaIndex := strings.Index(bsonTag, ","); commaIndex != -1 {
+		return bsonTag[:commaIndex]
+	}
+	return bsonTag
+}
+
+// bsonFields retorna los campos de primer nivel del struct con su nombre bson, omite _id y los ignorados
+func bsonFields(model any) map[string]any {
+	fields := map[string]any{}
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fields
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name := bsonFieldName(field)
+		if name == "-" || name == "_id" {
+			continue
+		}
+		fields[name] = v.Field(i).Interface()
+	}
+	return fields
+}
+
+func hasParentPath(dirty map[string]any, key string) bool {
+	for i := strings.LastIndex(key, "."); i != -1; i = strings.LastIndex(key[:i], ".") {
+		if _, ok := dirty[key[:i]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isNilValue(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
 // func Fill(model any, request any) Error {
 // 	modelValue := reflect.ValueOf(model)
 // 	requestValue := reflect.ValueOf(request)
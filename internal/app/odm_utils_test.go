@@ -0,0 +1,69 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestUpdateDocument(t *testing.T) {
+	var nilTime *time.Time
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name  string
+		dirty map[string]any
+		want  bson.D
+	}{
+		{"sin cambios", map[string]any{}, bson.D{}},
+		{
+			"set ordenado",
+			map[string]any{"name": "a", "email": "b"},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "email", Value: "b"}, {Key: "name", Value: "a"}}}},
+		},
+		{
+			"nil y puntero nil van a unset",
+			map[string]any{"deleted_at": nilTime, "phone": nil},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}, {Key: "phone", Value: ""}}}},
+		},
+		{
+			"set y unset",
+			map[string]any{"updated_at": &now, "deleted_at": nil},
+			bson.D{
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: &now}}},
+				{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}},
+			},
+		},
+		{
+			"el padre reemplaza a los hijos",
+			map[string]any{"profile": bson.M{"nickname": "x"}, "profile.nickname": "x", "profile.preferences.locale": "es"},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "profile", Value: bson.M{"nickname": "x"}}}}},
+		},
+		{
+			"hijos sin padre",
+			map[string]any{"profile.nickname": "x", "profile.preferences.locale": nil},
+			bson.D{
+				{Key: "$set", Value: bson.D{{Key: "profile.nickname", Value: "x"}}},
+				{Key: "$unset", Value: bson.D{{Key: "profile.preferences.locale", Value: ""}}},
+			},
+		},
+		{
+			"prefijo que no es padre",
+			map[string]any{"profile": "a", "profile_id": "b"},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "profile", Value: "a"}, {Key: "profile_id", Value: "b"}}}},
+		},
+		{
+			"padre nil quita los hijos",
+			map[string]any{"two_factor": nil, "two_factor.secret": "s"},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "two_factor", Value: ""}}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UpdateDocument(tt.dirty); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpdateDocument = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Paginator es la respuesta paginada: { data, meta, links }
+type Paginator struct {
+	Data    any            `json:"data"`
+	Meta    PaginatorMeta  `json:"meta"`
+	Links   PaginatorLinks `json:"links"`
+	path    string
+	fields  []string
+	include []string
+}
+
+type PaginatorMeta struct {
+	Page     int   `json:"page"`
+	PerPage  int   `json:"per_page"`
+	Total    int64 `json:"total"`
+	LastPage int   `json:"last_page"`
+}
+
+type PaginatorLinks struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last"`
+}
+
+// Paginate ejecuta el filtro paginado y cuenta el total en una sola consulta con $facet.
+// result debe ser un puntero a un slice (ej: &[]*model.User{})
+func (o *Odm) Paginate(result any, qf *QueryFilter) (*Paginator, Error) {
+	if qf.Page < 1 {
+		qf.Page = 1
+	}
+	if qf.PerPage < 1 {
+		qf.PerPage = NewQueryFilter().PerPage
+	}
+
+	match, err := qf.BsonD()
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: match}}}
+	if stage := qf.groupStage(); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
+
+	// se agrega _id al orden para que las paginas no repitan ni salten documentos
+	sortDoc := bson.D{}
+	for _, s := range qf.Sort {
+		sortDoc = append(sortDoc, bson.E{Key: s.Field, Value: s.Direction})
+	}
+	if !slices.ContainsFunc(qf.Sort, func(s Sort) bool { return s.Field == "_id" }) {
+		sortDoc = append(sortDoc, bson.E{Key: "_id", Value: 1})
+	}
+	// el $sort va antes del $facet para que pueda usar indices, dentro del facet no los usa y ordena en memoria
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sortDoc}})
+	data := bson.A{
+		bson.D{{Key: "$skip", Value: (qf.Page - 1) * qf.PerPage}},
+		bson.D{{Key: "$limit", Value: qf.PerPage}},
+	}
+	// las relaciones y la proyeccion van despues del limit para no hacer lookup de toda la coleccion
+	for _, lookup := range qf.Lookups {
+		data = append(data, lookup)
+	}
+	if stage := projectStage(o.Model, qf.Fields, qf.Include); stage != nil {
+		data = append(data, stage)
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "data", Value: data},
+		{Key: "total", Value: bson.A{bson.D{{Key: "$count", Value: "count"}}}},
+	}}})
+
+	ctx := context.TODO()
+	cursor, er := DB.Collection(o.Model.CollectionName()).Aggregate(ctx, pipeline)
+	if er != nil {
+		return nil, Errors.Mongo(er)
+	}
+	defer cursor.Close(ctx)
+
+	facet := struct {
+		Data  bson.RawValue `bson:"data"`
+		Total []struct {
+			Count int64 `bson:"count"`
+		} `bson:"total"`
+	}{}
+	if cursor.Next(ctx) {
+		if er := cursor.Decode(&facet); er != nil {
+			return nil, Errors.Mongo(er)
+		}
+	}
+	if er := cursor.Err(); er != nil {
+		return nil, Errors.Mongo(er)
+	}
+	if facet.Data.Type != 0 {
+		if er := facet.Data.Unmarshal(result); er != nil {
+			return nil, Errors.Mongo(er)
+		}
+	}
+
+	var total int64
+	if len(facet.Total) > 0 {
+		total = facet.Total[0].Count
+	}
+	lastPage := int((total + int64(qf.PerPage) - 1) / int64(qf.PerPage))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	return &Paginator{
+		Data: result,
+		Meta: PaginatorMeta{
+			Page:     qf.Page,
+			PerPage:  qf.PerPage,
+			Total:    total,
+			LastPage: lastPage,
+		},
+		path:    qf.Path,
+		fields:  qf.Fields,
+		include: qf.Include,
+	}, nil
+}
+
+// ResponsePaginated responde el paginador con los links armados desde la url de la peticion
+// y los mismos links en el encabezado Link
+func (ctx *HttpContext) ResponsePaginated(p *Paginator) {
+	path := p.path
+	if path == "" {
+		path = ctx.Request.URL.Path
+	}
+	query := ctx.Request.URL.Query()
+	pageURL := func(page int) string {
+		query.Set("page", strconv.Itoa(page))
+		return path + "?" + query.Encode()
+	}
+
+	p.Links = PaginatorLinks{
+		First: pageURL(1),
+		Last:  pageURL(p.Meta.LastPage),
+	}
+	if p.Meta.Page > 1 {
+		p.Links.Prev = pageURL(min(p.Meta.Page-1, p.Meta.LastPage))
+	}
+	if p.Meta.Page < p.Meta.LastPage {
+		p.Links.Next = pageURL(p.Meta.Page + 1)
+	}
+
+	data, err := SparseFields(p.Data, p.fields, p.include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	p.Data = data
+
+	ctx.Writer.Header().Set("Link", linkHeader(List{
+		{"first", p.Links.First},
+		{"prev", p.Links.Prev},
+		{"next", p.Links.Next},
+		{"last", p.Links.Last},
+	}))
+	ctx.ResponseOk(p)
+}
+
+// linkHeader arma el encabezado Link (RFC 8288), omite los links vacios
+func linkHeader(links List) string {
+	parts := []string{}
+	for _, link := range links {
+		href, _ := link.Value.(string)
+		if href == "" {
+			continue
+		}
+		parts = append(parts, "<"+href+">; rel=\""+link.Key+"\"")
+	}
+	return strings.Join(parts, ", ")
+}
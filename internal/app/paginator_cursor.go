@@ -0,0 +1,304 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// CursorPaginator es la respuesta paginada por cursor: { data, meta, links }
+type CursorPaginator struct {
+	Data    any                  `json:"data"`
+	Meta    CursorPaginatorMeta  `json:"meta"`
+	Links   CursorPaginatorLinks `json:"links"`
+	path    string
+	fields  []string
+	include []string
+}
+
+type CursorPaginatorMeta struct {
+	PerPage    int    `json:"per_page"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type CursorPaginatorLinks struct {
+	Prev string `json:"prev,omitempty"`
+	Next string `json:"next,omitempty"`
+}
+
+// pageCursor es lo que va codificado dentro del cursor, los valores conservan su tipo bson
+type pageCursor struct {
+	Fields    []string        `bson:"f"`
+	Values    []bson.RawValue `bson:"v"`
+	Direction int             `bson:"d"`
+}
+
+// CursorPaginate pagina por keyset: ordena por los campos de qf.Sort mas _id como desempate
+// y continua despues (o antes) de los valores guardados en el cursor.
+// result debe ser un puntero a un slice (ej: &[]*model.User{})
+func (o *Odm) CursorPaginate(result any, qf *QueryFilter) (*CursorPaginator, Error) {
+	qf.CursorPaginate()
+	if qf.PerPage < 1 {
+		qf.PerPage = NewQueryFilter().PerPage
+	}
+	if qf.Cursor == "" {
+		qf.CursorDirection = 1
+	}
+
+	match, err := qf.BsonD()
+	if err != nil {
+		return nil, err
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		qf.sortStage(),
+		{{Key: "$limit", Value: qf.PerPage + 1}}, // uno de mas para saber si hay otra pagina
+	}
+	pipeline = append(pipeline, qf.Lookups...)
+	// los campos del orden se proyectan siempre por que con ellos se arma el cursor
+	sortFields := []string{}
+	for _, s := range qf.keysetSort() {
+		sortFields = append(sortFields, s.Field)
+	}
+	if stage := projectStage(o.Model, qf.Fields, qf.Include, sortFields...); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
+
+	ctx := context.TODO()
+	cursor, er := DB.Collection(o.Model.CollectionName()).Aggregate(ctx, pipeline)
+	if er != nil {
+		return nil, Errors.Mongo(er)
+	}
+	rows := []bson.Raw{}
+	if er := cursor.All(ctx, &rows); er != nil {
+		return nil, Errors.Mongo(er)
+	}
+
+	hasMore := len(rows) > qf.PerPage
+	if hasMore {
+		rows = rows[:qf.PerPage]
+	}
+	if qf.CursorDirection == -1 {
+		// la pagina anterior se consulta en orden inverso
+		slices.Reverse(rows)
+	}
+
+	if err := decodeRows(rows, result); err != nil {
+		return nil, err
+	}
+
+	p := &CursorPaginator{
+		Data:    result,
+		Meta:    CursorPaginatorMeta{PerPage: qf.PerPage, HasMore: hasMore},
+		path:    qf.Path,
+		fields:  qf.Fields,
+		include: qf.Include,
+	}
+	if len(rows) == 0 {
+		return p, nil
+	}
+
+	// hacia adelante hay mas si sobro un registro o si venimos de regreso, igual hacia atras
+	hasNext := (qf.CursorDirection != -1 && hasMore) || qf.CursorDirection == -1
+	hasPrev := (qf.CursorDirection == -1 && hasMore) || (qf.CursorDirection != -1 && qf.Cursor != "")
+	if hasNext {
+		if p.Meta.NextCursor, err = qf.encodeCursor(rows[len(rows)-1], 1); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if p.Meta.PrevCursor, err = qf.encodeCursor(rows[0], -1); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// ResponseCursorPaginated responde el paginador con los links armados desde la url de la peticion
+// y los mismos links en el encabezado Link
+func (ctx *HttpContext) ResponseCursorPaginated(p *CursorPaginator) {
+	path := p.path
+	if path == "" {
+		path = ctx.Request.URL.Path
+	}
+	query := ctx.Request.URL.Query()
+	query.Del("page")
+	cursorURL := func(cursor string) string {
+		if cursor == "" {
+			return ""
+		}
+		query.Set("cursor", cursor)
+		return path + "?" + query.Encode()
+	}
+
+	p.Links = CursorPaginatorLinks{
+		Prev: cursorURL(p.Meta.PrevCursor),
+		Next: cursorURL(p.Meta.NextCursor),
+	}
+	data, err := SparseFields(p.Data, p.fields, p.include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	p.Data = data
+
+	if link := linkHeader(List{{"prev", p.Links.Prev}, {"next", p.Links.Next}}); link != "" {
+		ctx.Writer.Header().Set("Link", link)
+	}
+	ctx.ResponseOk(p)
+}
+
+func (qf *QueryFilter) isCursorMode() bool {
+	return qf.Page == 0 && qf.PerPage > 0
+}
+
+// keysetSort es qf.Sort + _id como desempate, son los campos que se guardan en el cursor
+func (qf *QueryFilter) keysetSort() []Sort {
+	sorts := slices.Clone(qf.Sort)
+	if !slices.ContainsFunc(sorts, func(s Sort) bool { return s.Field == "_id" }) {
+		sorts = append(sorts, Sort{Field: "_id", Direction: 1})
+	}
+	return sorts
+}
+
+// cursorSort es el orden de la consulta, invertido si se pide la pagina anterior
+func (qf *QueryFilter) cursorSort() []Sort {
+	sorts := qf.keysetSort()
+	if qf.CursorDirection == -1 {
+		for i := range sorts {
+			sorts[i].Direction *= -1
+		}
+	}
+	return sorts
+}
+
+// keysetClause arma el $or para continuar despues de los valores del cursor:
+// (a > va) or (a = va and b > vb) or (a = va and b = vb and _id > vid)
+// la igualdad con null tambien encuentra los documentos sin el campo, asi los nulos desempatan por _id.
+// La rama de _id siempre queda por que _id nunca es null, el $or no sale vacio
+func (qf *QueryFilter) keysetClause() (bson.E, Error) {
+	pc, err := qf.decodeCursor(qf.Cursor)
+	if err != nil {
+		return bson.E{}, err
+	}
+	qf.CursorDirection = pc.Direction
+
+	sorts := qf.cursorSort()
+	or := bson.A{}
+	for i, s := range sorts {
+		after, ok := keysetAfter(s, pc.Values[i])
+		if !ok {
+			continue
+		}
+		branch := bson.D{}
+		for j := 0; j < i; j++ {
+			branch = append(branch, bson.E{Key: sorts[j].Field, Value: pc.Values[j]})
+		}
+		or = append(or, append(branch, after))
+	}
+	return bson.E{Key: "$or", Value: or}, nil
+}
+
+// keysetAfter es la condicion "viene despues de value" para un campo del orden.
+// Mongo ordena null (y el campo ausente) antes que cualquier valor pero $gt y $lt no comparan entre tipos:
+// ascendente despues de null va todo lo que no es null, descendente despues de un valor tambien van los null
+// y descendente despues de null no hay nada (ok = false)
+func keysetAfter(s Sort, value bson.RawValue) (bson.E, bool) {
+	isNull := value.Type == bson.TypeNull || value.Type == bson.TypeUndefined
+	if s.Direction == -1 {
+		if isNull {
+			return bson.E{}, false
+		}
+		return bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: s.Field, Value: bson.D{{Key: "$lt", Value: value}}}},
+			bson.D{{Key: s.Field, Value: nil}},
+		}}, true
+	}
+	if isNull {
+		return bson.E{Key: s.Field, Value: bson.D{{Key: "$ne", Value: nil}}}, true
+	}
+	return bson.E{Key: s.Field, Value: bson.D{{Key: "$gt", Value: value}}}, true
+}
+
+// encodeCursor guarda los valores de orden del documento, base64 para que el cliente lo trate como opaco
+func (qf *QueryFilter) encodeCursor(row bson.Raw, direction int) (string, Error) {
+	pc := pageCursor{Direction: direction}
+	for _, s := range qf.keysetSort() {
+		value, er := row.LookupErr(strings.Split(s.Field, ".")...)
+		if er != nil {
+			value = bson.RawValue{Type: bson.TypeNull}
+		}
+		pc.Fields = append(pc.Fields, s.Field)
+		pc.Values = append(pc.Values, value)
+	}
+
+	data, er := bson.Marshal(pc)
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor valida que el cursor corresponda al orden actual de la consulta
+func (qf *QueryFilter) decodeCursor(cursor string) (*pageCursor, Error) {
+	invalid := Errors.BadRequestf("The cursor is invalid")
+
+	data, er := base64.RawURLEncoding.DecodeString(cursor)
+	if er != nil {
+		return nil, invalid
+	}
+	pc := &pageCursor{}
+	if er := bson.Unmarshal(data, pc); er != nil {
+		return nil, invalid
+	}
+	if pc.Direction != 1 && pc.Direction != -1 {
+		return nil, invalid
+	}
+
+	sorts := qf.keysetSort()
+	if len(pc.Fields) != len(sorts) || len(pc.Values) != len(sorts) {
+		return nil, Errors.BadRequestf("The cursor does not match the current sort")
+	}
+	for i, s := range sorts {
+		if pc.Fields[i] != s.Field {
+			return nil, Errors.BadRequestf("The cursor does not match the current sort")
+		}
+	}
+	return pc, nil
+}
+
+// decodeRows decodifica los documentos en el slice al que apunta result
+func decodeRows(rows []bson.Raw, result any) Error {
+	slice := reflect.ValueOf(result)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return Errors.InternalServerErrorf("The result must be a pointer to a slice")
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+
+	values := reflect.MakeSlice(slice.Type(), 0, len(rows))
+	for _, row := range rows {
+		if elemType.Kind() == reflect.Ptr {
+			elem := reflect.New(elemType.Elem())
+			if er := bson.Unmarshal(row, elem.Interface()); er != nil {
+				return Errors.Mongo(er)
+			}
+			values = reflect.Append(values, elem)
+		} else {
+			elem := reflect.New(elemType)
+			if er := bson.Unmarshal(row, elem.Interface()); er != nil {
+				return Errors.Mongo(er)
+			}
+			values = reflect.Append(values, elem.Elem())
+		}
+	}
+	slice.Set(values)
+	return nil
+}
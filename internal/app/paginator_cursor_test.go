@@ -0,0 +1,142 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := bson.NewObjectID()
+	tests := []struct {
+		name      string
+		sort      []Sort
+		row       bson.D
+		direction int
+		types     []bson.Type
+	}{
+		{
+			name:      "solo _id",
+			row:       bson.D{{Key: "_id", Value: id}},
+			direction: 1,
+			types:     []bson.Type{bson.TypeObjectID},
+		},
+		{
+			name:      "campo y desempate",
+			sort:      []Sort{{"name", 1}},
+			row:       bson.D{{Key: "_id", Value: id}, {Key: "name", Value: "Bogota"}},
+			direction: 1,
+			types:     []bson.Type{bson.TypeString, bson.TypeObjectID},
+		},
+		{
+			name:      "campo null",
+			sort:      []Sort{{"deleted_at", -1}},
+			row:       bson.D{{Key: "_id", Value: id}, {Key: "deleted_at", Value: nil}},
+			direction: -1,
+			types:     []bson.Type{bson.TypeNull, bson.TypeObjectID},
+		},
+		{
+			name:      "campo ausente queda null",
+			sort:      []Sort{{"profile.city_id", 1}},
+			row:       bson.D{{Key: "_id", Value: id}},
+			direction: 1,
+			types:     []bson.Type{bson.TypeNull, bson.TypeObjectID},
+		},
+		{
+			name:      "campo anidado",
+			sort:      []Sort{{"profile.birthday", 1}, {"_id", -1}},
+			row:       bson.D{{Key: "_id", Value: id}, {Key: "profile", Value: bson.D{{Key: "birthday", Value: time.Unix(0, 0)}}}},
+			direction: 1,
+			types:     []bson.Type{bson.TypeDateTime, bson.TypeObjectID},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qf := NewQueryFilter()
+			qf.Sort = tt.sort
+			row, er := bson.Marshal(tt.row)
+			if er != nil {
+				t.Fatal(er)
+			}
+			cursor, err := qf.encodeCursor(row, tt.direction)
+			if err != nil {
+				t.Fatalf("encodeCursor: %v", err)
+			}
+			pc, err := qf.decodeCursor(cursor)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if pc.Direction != tt.direction {
+				t.Errorf("Direction = %d, want %d", pc.Direction, tt.direction)
+			}
+			if len(pc.Values) != len(tt.types) {
+				t.Fatalf("len(Values) = %d, want %d", len(pc.Values), len(tt.types))
+			}
+			for i, typ := range tt.types {
+				if pc.Values[i].Type != typ {
+					t.Errorf("Values[%d].Type = %s, want %s", i, pc.Values[i].Type, typ)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	qf := NewQueryFilter()
+	qf.Sort = []Sort{{"name", 1}}
+	row, _ := bson.Marshal(bson.D{{Key: "_id", Value: bson.NewObjectID()}, {Key: "name", Value: "a"}})
+	valid, err := qf.encodeCursor(row, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := NewQueryFilter()
+	other.Sort = []Sort{{"email", 1}}
+
+	tests := []struct {
+		name   string
+		qf     *QueryFilter
+		cursor string
+	}{
+		{"no es base64", qf, "%%%"},
+		{"no es bson", qf, "YWJj"},
+		{"otro orden", other, valid},
+		{"sin orden", NewQueryFilter(), valid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.qf.decodeCursor(tt.cursor); err == nil || err.GetStatus() != 400 {
+				t.Errorf("decodeCursor(%q) = %v, want 400", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestKeysetAfterNull(t *testing.T) {
+	null := bson.RawValue{Type: bson.TypeNull}
+	tests := []struct {
+		name string
+		sort Sort
+		ok   bool
+		op   string
+	}{
+		{"asc despues de null", Sort{"deleted_at", 1}, true, "$ne"},
+		{"desc despues de null", Sort{"deleted_at", -1}, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, ok := keysetAfter(tt.sort, null)
+			if ok != tt.ok {
+				t.Fatalf("ok = %t, want %t", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			cond, _ := e.Value.(bson.D)
+			if len(cond) != 1 || cond[0].Key != tt.op {
+				t.Errorf("condicion = %v, want %s", e.Value, tt.op)
+			}
+		})
+	}
+}
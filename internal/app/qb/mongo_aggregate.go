@@ -0,0 +1,169 @@
+package qb
+
+import (
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// etapas de agregacion ----------------------------------------------------------------
+
+func Group(id any, accumulators ...bson.E) bson.D {
+	group := bson.D{{Key: "_id", Value: id}}
+	group = append(group, accumulators...)
+	return bson.D{{Key: "$group", Value: group}}
+}
+
+func Project(value ...bson.E) bson.D {
+	return bson.D{{Key: "$project", Value: bson.D(value)}}
+}
+
+func AddFields(value ...bson.E) bson.D {
+	return bson.D{{Key: "$addFields", Value: bson.D(value)}}
+}
+
+func ReplaceWith(value any) bson.D {
+	return bson.D{{Key: "$replaceWith", Value: value}}
+}
+
+// Sort ordena por los campos, Element("created_at", -1)
+func Sort(value ...bson.E) bson.D {
+	return bson.D{{Key: "$sort", Value: bson.D(value)}}
+}
+
+func Skip(value int) bson.D {
+	return bson.D{{Key: "$skip", Value: value}}
+}
+
+func Limit(value int) bson.D {
+	return bson.D{{Key: "$limit", Value: value}}
+}
+
+func Count(as string) bson.D {
+	return bson.D{{Key: "$count", Value: as}}
+}
+
+func SortByCount(expression any) bson.D {
+	return bson.D{{Key: "$sortByCount", Value: expression}}
+}
+
+// Facet ejecuta varios pipelines sobre los mismos documentos, cada uno con FacetPipeline
+func Facet(facets ...bson.E) bson.D {
+	return bson.D{{Key: "$facet", Value: bson.D(facets)}}
+}
+
+func FacetPipeline(name string, stages ...bson.D) bson.E {
+	pipeline := bson.A{}
+	for _, stage := range stages {
+		pipeline = append(pipeline, stage)
+	}
+	return bson.E{Key: name, Value: pipeline}
+}
+
+// Bucket agrupa por rangos, boundaries son los limites (ej: 0, 18, 30, 60) y
+// defaultBucket es donde quedan los valores por fuera de los limites
+func Bucket(groupBy any, boundaries []any, defaultBucket any, output ...bson.E) bson.D {
+	bucket := bson.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "boundaries", Value: bson.A(boundaries)},
+	}
+	if defaultBucket != nil {
+		bucket = append(bucket, bson.E{Key: "default", Value: defaultBucket})
+	}
+	if len(output) > 0 {
+		bucket = append(bucket, bson.E{Key: "output", Value: bson.D(output)})
+	}
+	return bson.D{{Key: "$bucket", Value: bucket}}
+}
+
+// BucketAuto agrupa en la cantidad de rangos indicada y mongo calcula los limites
+func BucketAuto(groupBy any, buckets int, output ...bson.E) bson.D {
+	bucket := bson.D{
+		{Key: "groupBy", Value: groupBy},
+		{Key: "buckets", Value: buckets},
+	}
+	if len(output) > 0 {
+		bucket = append(bucket, bson.E{Key: "output", Value: bson.D(output)})
+	}
+	return bson.D{{Key: "$bucketAuto", Value: bucket}}
+}
+
+// SetWindowFields calcula valores sobre una ventana de documentos (acumulados, promedios moviles, rank)
+func SetWindowFields(partitionBy any, sortBy bson.D, output ...bson.E) bson.D {
+	window := bson.D{}
+	if partitionBy != nil {
+		window = append(window, bson.E{Key: "partitionBy", Value: partitionBy})
+	}
+	if len(sortBy) > 0 {
+		window = append(window, bson.E{Key: "sortBy", Value: sortBy})
+	}
+	window = append(window, bson.E{Key: "output", Value: bson.D(output)})
+	return bson.D{{Key: "$setWindowFields", Value: window}}
+}
+
+// Window agrega la ventana a un acumulador de SetWindowFields, Window(Sum("total", "$count"), Documents("unbounded", "current"))
+func Window(accumulator bson.E, bounds ...bson.E) bson.E {
+	value, _ := accumulator.Value.(bson.D)
+	value = append(value, bson.E{Key: "window", Value: bson.D(bounds)})
+	return bson.E{Key: accumulator.Key, Value: value}
+}
+
+func Documents(lower any, upper any) bson.E {
+	return bson.E{Key: "documents", Value: bson.A{lower, upper}}
+}
+
+// Range ventana por rango de valores del sortBy, con fechas se acompaña de Unit("day")
+func Range(lower any, upper any) bson.E {
+	return bson.E{Key: "range", Value: bson.A{lower, upper}}
+}
+
+func Unit(unit string) bson.E {
+	return bson.E{Key: "unit", Value: unit}
+}
+
+// expresiones de fecha ----------------------------------------------------------------
+
+// DateTrunc corta la fecha a la unidad (year, quarter, month, week, day, hour, minute), sirve para histogramas
+func DateTrunc(date any, unit string, timezone ...string) bson.D {
+	trunc := bson.D{
+		{Key: "date", Value: date},
+		{Key: "unit", Value: unit},
+	}
+	if len(timezone) > 0 && timezone[0] != "" {
+		trunc = append(trunc, bson.E{Key: "timezone", Value: timezone[0]})
+	}
+	return bson.D{{Key: "$dateTrunc", Value: trunc}}
+}
+
+// acumuladores ----------------------------------------------------------------
+// se usan en Group, Bucket, BucketAuto y SetWindowFields: Group("$city_id", Sum("total", 1), Avg("age", "$age"))
+
+func Sum(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$sum", Value: expression}}}
+}
+
+func Avg(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$avg", Value: expression}}}
+}
+
+func Min(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$min", Value: expression}}}
+}
+
+func Max(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$max", Value: expression}}}
+}
+
+func First(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$first", Value: expression}}}
+}
+
+func Last(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$last", Value: expression}}}
+}
+
+func Push(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$push", Value: expression}}}
+}
+
+func AddToSet(as string, expression any) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$addToSet", Value: expression}}}
+}
@@ -0,0 +1,133 @@
+package qb
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// maximo de metricas que se pueden pedir en una consulta
+const ANALYTICS_MAX_METRICS = 10
+
+// Analytics arma un pipeline de agregacion para graficas con los parametros de la url.
+// Los filtros y el group_by se toman del QueryFilter y las metricas de ?metrics=
+//
+//	?group_by=profile.city_id&metrics=count
+//	?group_by=created_at:month&metrics=count,avg:age,max:age
+//
+// Cada fila queda plana: { "profile_city_id": ..., "count": 10, "avg_age": 31.5 }
+func Analytics(ctx *app.HttpContext, qf *app.QueryFilter, allowed ...string) (mongo.Pipeline, app.Error) {
+	accumulators := []bson.E{}
+	for _, param := range ctx.Request.URL.Query()["metrics"] {
+		for _, metric := range strings.Split(param, ",") {
+			metric = strings.TrimSpace(metric)
+			if metric == "" {
+				continue
+			}
+			accumulator, err := analyticsMetric(metric, allowed, qf.Schema)
+			if err != nil {
+				return nil, err
+			}
+			accumulators = append(accumulators, accumulator)
+		}
+	}
+	if len(accumulators) == 0 {
+		accumulators = append(accumulators, Sum("count", 1))
+	}
+	if len(accumulators) > ANALYTICS_MAX_METRICS {
+		return nil, app.Errors.BadRequestf("The metrics exceeds :max items", app.E("max", ANALYTICS_MAX_METRICS))
+	}
+
+	match, err := qf.BsonD()
+	if err != nil {
+		return nil, err
+	}
+
+	groupID := bson.D{}
+	project := []bson.E{Element("_id", 0)}
+	sort := []bson.E{}
+	for _, field := range qf.GroupBy {
+		alias, expression := qf.GroupExpression(field)
+		groupID = append(groupID, Element(alias, expression))
+		project = append(project, Element(alias, "$_id."+alias))
+		sort = append(sort, Element(alias, 1))
+	}
+	for _, accumulator := range accumulators {
+		project = append(project, Element(accumulator.Key, 1))
+	}
+
+	var id any = groupID
+	if len(groupID) == 0 {
+		id = nil
+	}
+	pipeline := Pipeline(
+		bson.D{{Key: "$match", Value: match}},
+		Group(id, accumulators...),
+		Project(project...),
+	)
+	if len(sort) > 0 {
+		pipeline = append(pipeline, Sort(sort...))
+	}
+	pipeline = append(pipeline, Limit(app.QUERY_MAX_PER_PAGE))
+
+	return pipeline, nil
+}
+
+// analyticsMetric convierte count, sum:campo, avg:campo, min:campo o max:campo en el acumulador.
+// sum y avg solo se aceptan en campos numericos del modelo (ver qf.SetModel), min y max tambien sirven con fechas
+func analyticsMetric(metric string, allowed []string, schema *app.ModelSchema) (bson.E, app.Error) {
+	if metric == "count" {
+		return Sum("count", 1), nil
+	}
+
+	operator, field, ok := strings.Cut(metric, ":")
+	if !ok || field == "" {
+		return bson.E{}, app.Errors.BadRequestf("The metric :metric must be count or operator:field", app.E("metric", metric))
+	}
+	if !slices.Contains(allowed, field) {
+		return bson.E{}, app.Errors.BadRequestf("The field :field is not allowed in metrics", app.E("field", field))
+	}
+
+	if (operator == "sum" || operator == "avg") && !numericField(schema, field) {
+		return bson.E{}, app.Errors.BadRequestf("The metric :operator requires a numeric field, :field is not", app.E("operator", operator), app.E("field", field))
+	}
+
+	as := operator + "_" + strings.ReplaceAll(field, ".", "_")
+	switch operator {
+	case "sum":
+		return Sum(as, "$"+field), nil
+	case "avg":
+		return Avg(as, "$"+field), nil
+	case "min":
+		return Min(as, "$"+field), nil
+	case "max":
+		return Max(as, "$"+field), nil
+	}
+	return bson.E{}, app.Errors.BadRequestf("The metric operator :operator is not supported", app.E("operator", operator))
+}
+
+// numericField indica si el campo del modelo es un numero, sin modelo no se puede saber y se rechaza
+func numericField(schema *app.ModelSchema, field string) bool {
+	if schema == nil {
+		return false
+	}
+	f, ok := schema.Field(field)
+	if !ok {
+		return false
+	}
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
@@ -107,79 +107,58 @@ func manyToManyWith(collection string, localField string, with ...bson.D) bson.D
 	}
 }
 
-// HasOne crea un bson.D para realizar una busqueda con relacion hasOne
-func HasOne(collection string, foreignField string, as string, with ...bson.D) (bson.D, bson.D) {
-	if len(with) > 0 {
-		return hasOneWith(collection, foreignField, as, with...)
-	}
-
-	return bson.D{{Key: "$lookup", Value: bson.D{
-			{Key: "from", Value: collection},
-			{Key: "localField", Value: "_id"},
-			{Key: "foreignField", Value: foreignField},
-			{Key: "as", Value: as},
-		}}},
-		bson.D{{Key: "$unwind", Value: bson.D{
-			{Key: "path", Value: "$" + as},
-			{Key: "preserveNullAndEmptyArrays", Value: true},
-		}}}
-}
-
-// HasOneWith crea un bson.D para realizar una busqueda con relacion hasOne habilitando relaciones en los documentos hijos
-func hasOneWith(collection string, foreignField string, as string, with ...bson.D) (bson.D, bson.D) {
+// HasOne crea el $lookup y el $unwind de una relacion hasOne, el hijo guarda el _id local en foreignField.
+// Si no hay hijo el campo queda vacio (preserveNullAndEmptyArrays). with son etapas para el documento
+// relacionado: relaciones anidadas o una proyeccion, HasOne("profiles", "user_id", "profile", Project(...))
+func HasOne(collection string, foreignField string, as string, with ...bson.D) []bson.D {
 	parentID := collection + "_pid"
 
-	// pipeline base para filtrar por relación padre-hijo
 	pipeline := bson.A{
 		bson.D{{Key: "$match", Value: bson.D{
 			{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$" + foreignField, "$$" + parentID}}}},
 		}}},
+		bson.D{{Key: "$limit", Value: 1}},
 	}
-
 	for _, w := range with {
 		pipeline = append(pipeline, w)
 	}
 
-	return bson.D{{Key: "$lookup", Value: bson.D{
+	return []bson.D{
+		{{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: collection},
 			{Key: "let", Value: bson.D{{Key: parentID, Value: "$_id"}}},
 			{Key: "pipeline", Value: pipeline},
 			{Key: "as", Value: as},
-		}}}, bson.D{
-			{Key: "$unwind", Value: bson.D{
-				{Key: "path", Value: "$" + as},
-				{Key: "preserveNullAndEmptyArrays", Value: true},
-			}}}
-}
-
-// BelongsTo crea un bson.D para realizar una busqueda con relacion belongsTo
-func BelongsTo(collection string, localField string, as string) []bson.D {
-	return []bson.D{
-		{{Key: "$lookup", Value: bson.D{
-			{Key: "from", Value: collection},       // colección relacionada
-			{Key: "localField", Value: localField}, // campo en el documento local (foreign key)
-			{Key: "foreignField", Value: "_id"},    // campo en la colección relacionada
-			{Key: "as", Value: as},                 // nombre del campo resultado
-		}}},
-
-		{{Key: "$unwind", Value: bson.D{
-			{Key: "path", Value: "$" + as},
-			{Key: "preserveNullAndEmptyArrays", Value: true},
 		}}},
+		unwindPreserve(as),
 	}
 }
 
-// BelongsToWith crea un bson.D para realizar una busqueda con relacion belongsTo habilitando relaciones en el documentos padre
-func BelongsToWith(collection string, localField string, as string, with ...bson.D) []bson.D {
-	foreignID := collection + "_id"
+// BelongsTo crea el $lookup y el $unwind de una relacion belongsTo, localField guarda el _id del padre
+// (ej: access_tokens.user_id → users). Si no existe el padre el campo queda vacio.
+// with son etapas para el documento relacionado: relaciones anidadas o una proyeccion,
+//
+//	BelongsTo("cities", "profile.city_id", "city", BelongsTo("states", "state_id", "state")...)
+//	BelongsTo("users", "user_id", "user", Project(Element("password", 0)))
+func BelongsTo(collection string, localField string, as string, with ...bson.D) []bson.D {
+	if len(with) == 0 {
+		return []bson.D{
+			{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: collection},       // colección relacionada
+				{Key: "localField", Value: localField}, // campo en el documento local (foreign key)
+				{Key: "foreignField", Value: "_id"},    // campo en la colección relacionada
+				{Key: "as", Value: as},                 // nombre del campo resultado
+			}}},
+			unwindPreserve(as),
+		}
+	}
 
+	foreignID := collection + "_id"
 	pipeline := bson.A{
 		bson.D{{Key: "$match", Value: bson.D{
 			{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$_id", "$$" + foreignID}}}},
 		}}},
 	}
-
-	// Agregar lookups anidados si existen
 	for _, w := range with {
 		pipeline = append(pipeline, w)
 	}
@@ -191,14 +170,18 @@ func BelongsToWith(collection string, localField string, as string, with ...bson
 			{Key: "pipeline", Value: pipeline},
 			{Key: "as", Value: as},
 		}}},
-
-		{{Key: "$unwind", Value: bson.D{
-			{Key: "path", Value: "$" + as},
-			{Key: "preserveNullAndEmptyArrays", Value: true},
-		}}},
+		unwindPreserve(as),
 	}
 }
 
+// unwindPreserve convierte la lista del $lookup en un solo documento sin descartar el padre si esta vacia
+func unwindPreserve(as string) bson.D {
+	return bson.D{{Key: "$unwind", Value: bson.D{
+		{Key: "path", Value: "$" + as},
+		{Key: "preserveNullAndEmptyArrays", Value: true},
+	}}}
+}
+
 // BelongsToMany crea un bson.D para realizar una busqueda con relacion manyToMany inverso
 func BelongsToMany(collection string, foreignArrayField string, as string) bson.D {
 	return bson.D{
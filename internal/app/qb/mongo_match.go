@@ -184,6 +184,19 @@ func Set(value ...bson.E) bson.D {
 	return bson.D{bson.E{Key: "$set", Value: value}}
 }
 
+// SetOnInsert solo escribe los campos si el upsert inserta el documento
+func SetOnInsert(value ...bson.E) bson.D {
+	return bson.D{bson.E{Key: "$setOnInsert", Value: value}}
+}
+
+func Inc(value ...bson.E) bson.D {
+	return bson.D{bson.E{Key: "$inc", Value: value}}
+}
+
+func Pull(value ...bson.E) bson.D {
+	return bson.D{bson.E{Key: "$pull", Value: value}}
+}
+
 // operadores miselanios ----------------------------------------------------------------
 
 func Rand() bson.E {
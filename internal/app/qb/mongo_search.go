@@ -0,0 +1,133 @@
+package qb
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// radio de la tierra en km, $centerSphere recibe el radio en radianes
+const EARTH_RADIUS_KM = 6378.1
+
+// busqueda de texto ----------------------------------------------------------------
+// requiere un indice de texto (app.TextIndex), solo puede haber uno por coleccion
+
+// Text busca las palabras en los campos del indice de texto, Match(Text("bogota"))
+func Text(search string, language ...string) bson.E {
+	text := bson.D{{Key: "$search", Value: search}}
+	if len(language) > 0 && language[0] != "" {
+		text = append(text, bson.E{Key: "$language", Value: language[0]})
+	}
+	return bson.E{Key: "$text", Value: text}
+}
+
+// TextScore es la relevancia de $text, AddFields(TextScore("score")) y luego Sort(TextScore("score"))
+func TextScore(as string) bson.E {
+	return bson.E{Key: as, Value: bson.D{{Key: "$meta", Value: "textScore"}}}
+}
+
+// busqueda geografica ----------------------------------------------------------------
+// $geoNear y $near requieren un indice 2dsphere (app.GeoIndex), las distancias son en metros
+
+// GeoNear ordena por cercania al punto y guarda la distancia en distanceField, debe ser la primera etapa del pipeline.
+// GeoNear(app.NewGeoPoint(lat, lng), "distance", MaxDistance(10000), DistanceMultiplier(0.001))
+func GeoNear(point *app.GeoPoint, distanceField string, opts ...bson.E) bson.D {
+	near := bson.D{
+		{Key: "near", Value: point},
+		{Key: "distanceField", Value: distanceField},
+		{Key: "spherical", Value: true},
+	}
+	near = append(near, opts...)
+	return bson.D{{Key: "$geoNear", Value: near}}
+}
+
+// opciones de GeoNear
+
+func MaxDistance(meters float64) bson.E {
+	return bson.E{Key: "maxDistance", Value: meters}
+}
+
+func MinDistance(meters float64) bson.E {
+	return bson.E{Key: "minDistance", Value: meters}
+}
+
+// NearQuery filtra los documentos dentro del $geoNear (no se puede usar $match antes)
+func NearQuery(filter bson.D) bson.E {
+	return bson.E{Key: "query", Value: filter}
+}
+
+// DistanceMultiplier convierte la distancia, 0.001 para kilometros
+func DistanceMultiplier(multiplier float64) bson.E {
+	return bson.E{Key: "distanceMultiplier", Value: multiplier}
+}
+
+// GeoKey el campo a usar si la coleccion tiene varios indices 2dsphere
+func GeoKey(field string) bson.E {
+	return bson.E{Key: "key", Value: field}
+}
+
+// Near es el operador para Find, Where("location", Near(point, 5000, 0)), con 0 no se limita la distancia.
+// En agregaciones se usa GeoNear.
+func Near(point *app.GeoPoint, maxMeters float64, minMeters float64) bson.E {
+	near := bson.D{{Key: "$geometry", Value: point}}
+	if maxMeters > 0 {
+		near = append(near, bson.E{Key: "$maxDistance", Value: maxMeters})
+	}
+	if minMeters > 0 {
+		near = append(near, bson.E{Key: "$minDistance", Value: minMeters})
+	}
+	return bson.E{Key: "$near", Value: near}
+}
+
+// GeoWithin documentos dentro de la figura, Where("location", GeoWithin(Circle(lat, lng, 10))).
+// No ordena por distancia y no requiere indice, sirve tambien para contar.
+func GeoWithin(shape bson.E) bson.E {
+	return bson.E{Key: "$geoWithin", Value: bson.D{shape}}
+}
+
+// Circle es un circulo sobre la esfera, el radio en km
+func Circle(latitude float64, longitude float64, km float64) bson.E {
+	return bson.E{Key: "$centerSphere", Value: bson.A{bson.A{longitude, latitude}, km / EARTH_RADIUS_KM}}
+}
+
+// Box es el rectangulo entre la esquina suroeste y la noreste
+func Box(minLatitude float64, minLongitude float64, maxLatitude float64, maxLongitude float64) bson.E {
+	return Polygon(
+		[2]float64{minLatitude, minLongitude},
+		[2]float64{minLatitude, maxLongitude},
+		[2]float64{maxLatitude, maxLongitude},
+		[2]float64{maxLatitude, minLongitude},
+	)
+}
+
+// Polygon recibe los vertices como {lat, lng}, el anillo se cierra solo
+func Polygon(points ...[2]float64) bson.E {
+	ring := bson.A{}
+	for _, p := range points {
+		ring = append(ring, bson.A{p[1], p[0]})
+	}
+	if len(points) > 0 && points[0] != points[len(points)-1] {
+		ring = append(ring, bson.A{points[0][1], points[0][0]})
+	}
+	return bson.E{Key: "$geometry", Value: bson.D{
+		{Key: "type", Value: "Polygon"},
+		{Key: "coordinates", Value: bson.A{ring}},
+	}}
+}
+
+// idiomas de $text ----------------------------------------------------------
+
+// idiomas que acepta $language de $text (codigos ISO 639-1), los demas buscan sin stemming ("none")
+var TextLanguages = []string{"da", "de", "en", "es", "fi", "fr", "hu", "it", "nb", "nl", "pt", "ro", "ru", "sv", "tr"}
+
+// TextLanguage convierte el locale del Accept-Language en el $language de $text, Text(search, TextLanguage(ctx.Locale()))
+func TextLanguage(locale string) string {
+	language, _, _ := strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+	language = strings.ToLower(language)
+	if !slices.Contains(TextLanguages, language) {
+		return "none"
+	}
+	return language
+}
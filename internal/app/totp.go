@@ -0,0 +1,93 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP segun RFC 6238 con los valores que usan las apps de autenticacion (SHA1, 6 digitos, 30 segundos)
+const TOTP_DIGITS = 6
+const TOTP_PERIOD = 30
+
+// pasos de tolerancia antes y despues del actual por si el reloj del celular esta corrido
+const TOTP_SKEW = 1
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPSecret genera un secreto de 160 bits en base32
+func TOTPSecret() (string, Error) {
+	bytes := make([]byte, 20)
+	if _, er := rand.Read(bytes); er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	return totpEncoding.EncodeToString(bytes), nil
+}
+
+// TOTPURI es el otpauth:// que se muestra como QR en la app de autenticacion
+func TOTPURI(secret string, account string) string {
+	label := url.PathEscape(Env.APP_NAME) + ":" + url.PathEscape(account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", Env.APP_NAME)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	query.Set("period", fmt.Sprint(TOTP_PERIOD))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPStep es el numero de periodo de t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / TOTP_PERIOD
+}
+
+// TOTPCode calcula el codigo del periodo step
+func TOTPCode(secret string, step int64) (string, Error) {
+	key, er := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if er != nil {
+		return "", Errors.InternalServerError(er)
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for range TOTP_DIGITS {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, value%mod), nil
+}
+
+// TOTPVerify valida el codigo en la ventana de TOTP_SKEW y retorna el periodo que coincidio.
+// Solo se aceptan periodos mayores a lastStep para que un codigo no se pueda usar dos veces
+func TOTPVerify(secret string, code string, t time.Time, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+	current := TOTPStep(t)
+	for step := current - TOTP_SKEW; step <= current+TOTP_SKEW; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
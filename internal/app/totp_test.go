@@ -0,0 +1,71 @@
+package app
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// secreto de los vectores de prueba del RFC 6238 (SHA1), los codigos son los 6 ultimos digitos de los de 8
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", step, err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		step     int64
+		ok       bool
+	}{
+		{"actual", codeAt(current), 0, current, true},
+		{"anterior", codeAt(current - 1), 0, current - 1, true},
+		{"siguiente", codeAt(current + 1), 0, current + 1, true},
+		{"dos antes", codeAt(current - 2), 0, 0, false},
+		{"dos despues", codeAt(current + 2), 0, 0, false},
+		{"ya usado", codeAt(current), current, 0, false},
+		{"anterior al ultimo usado", codeAt(current - 1), current - 1, 0, false},
+		{"con espacios", " " + codeAt(current) + " ", 0, current, true},
+		{"corto", codeAt(current)[:5], 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := TOTPVerify(rfcSecret, tt.code, now, tt.lastStep)
+			if ok != tt.ok || step != tt.step {
+				t.Errorf("TOTPVerify = (%d, %t), want (%d, %t)", step, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
@@ -115,6 +115,11 @@ func ValidateRules(ctx *HttpContext, req any, rules map[string][]string) Error {
 			}
 		}
 
+		// los punteros (ej: *float64 para distinguir 0 de no enviado) se validan por su valor
+		if value.Kind() == reflect.Ptr && !value.IsNil() {
+			value = value.Elem()
+		}
+
 		// Aplicar reglas
 		for _, rule := range r {
 			param := ""
@@ -216,6 +221,12 @@ func ValidateRules(ctx *HttpContext, req any, rules map[string][]string) Error {
 				err.Append(ValidateUUID(key, value.String()))
 			case "ulid":
 				err.Append(ValidateULID(key, value.String()))
+			case "timezone":
+				err.Append(ValidateTimezone(key, value.String()))
+			case "locale":
+				err.Append(ValidateLocale(key, value.String()))
+			case "date_format":
+				err.Append(ValidateDateFormat(key, value.String()))
 			case "ip":
 				err.Append(ValidateIP(key, value.String()))
 			case "ipv4":
@@ -333,24 +344,24 @@ func ValidateRules(ctx *HttpContext, req any, rules map[string][]string) Error {
 				if len(params) != 2 {
 					err.Appendf(key, "the exists rule must have two parameters")
 				} else {
-					var v any
-					if strings.HasSuffix(params[1], "_id") {
-						var er error
-						v, er = bson.ObjectIDFromHex(value.String())
-						if er != nil {
-							err.Appendf(key, "the value is not a valid id: :error", Entry{"error", er})
-						}
+					v, fe := ruleDatabaseValue(key, params[0], params[1], value)
+					if fe != nil {
+						err.Append(fe)
 					} else {
-						v = value.Interface()
+						err.Append(ValidateExists(key, params[0], params[1], v))
 					}
-					err.Append(ValidateExists(key, params[0], params[1], v))
 				}
 			case "unique":
 				params := strings.Split(param, ",")
 				if len(params) != 2 {
 					err.Appendf(key, "the unique rule must have two parameters")
 				} else {
-					err.Append(ValidateUnique(key, params[0], params[1], value.Interface(), ctx.Params["id"]))
+					v, fe := ruleDatabaseValue(key, params[0], params[1], value)
+					if fe != nil {
+						err.Append(fe)
+					} else {
+						err.Append(ValidateUnique(key, params[0], params[1], v, ctx.Params["id"]))
+					}
 				}
 			case "unique_in":
 				switch value.Kind() {
@@ -484,6 +495,42 @@ func getOtherFieldValueFromParam(val reflect.Value, param string) any {
 	return nil
 }
 
+// ruleDatabaseValue convierte el valor de la peticion al tipo del campo en la base de datos.
+// Si la coleccion esta registrada se usa el tipo del modelo, si no se asume ObjectID para los campos *_id
+func ruleDatabaseValue(attribute string, collection string, field string, value reflect.Value) (any, *FieldError) {
+	isObjectID := field == "_id" || strings.HasSuffix(field, "_id")
+	if schema, ok := GetModel(collection); ok {
+		f, ok := schema.Field(field)
+		if !ok {
+			PrintWarning("The field :field does not exist in the model :collection", Entry{"field", field}, Entry{"collection", collection})
+			return nil, &FieldError{
+				FieldName: attribute,
+				Message:   "The {attribute} failed to check in database.",
+				Placeholders: List{
+					{Key: "attribute", Value: attribute},
+				},
+			}
+		}
+		isObjectID = f.IsObjectID()
+	}
+
+	if isObjectID && value.Kind() == reflect.String {
+		oid, er := bson.ObjectIDFromHex(value.String())
+		if er != nil {
+			return nil, &FieldError{
+				FieldName: attribute,
+				Message:   "The {attribute} is not a valid id: {error}.",
+				Placeholders: List{
+					{Key: "attribute", Value: attribute},
+					{Key: "error", Value: er.Error()},
+				},
+			}
+		}
+		return oid, nil
+	}
+	return value.Interface(), nil
+}
+
 func ValidateMinNumber[T constraints.Integer | constraints.Float](attribute string, value T, limit T) *FieldError {
 	if value < limit {
 		return &FieldError{
@@ -1058,6 +1105,49 @@ func ValidateULID(attribute, value string) *FieldError {
 	return nil
 }
 
+// ValidateTimezone la zona horaria debe existir en la base de datos IANA (ej: America/Bogota)
+func ValidateTimezone(attribute, value string) *FieldError {
+	if _, er := time.LoadLocation(value); er != nil || value == "" || value == "Local" {
+		return &FieldError{
+			FieldName: attribute,
+			Message:   "The {attribute} must be a valid timezone.",
+			Placeholders: List{
+				{"attribute", attribute},
+			},
+		}
+	}
+	return nil
+}
+
+// ValidateLocale idioma con region opcional (ej: es, es-CO, pt-BR)
+func ValidateLocale(attribute, value string) *FieldError {
+	localeRegex := regexp.MustCompile(`^[a-z]{2,3}(-[A-Z][a-z]{3})?(-([A-Z]{2}|\d{3}))?$`)
+	if !localeRegex.MatchString(value) {
+		return &FieldError{
+			FieldName: attribute,
+			Message:   "The {attribute} must be a valid locale.",
+			Placeholders: List{
+				{"attribute", attribute},
+			},
+		}
+	}
+	return nil
+}
+
+// ValidateDateFormat el formato debe ser uno de DateFormats (ej: d/m/Y)
+func ValidateDateFormat(attribute, value string) *FieldError {
+	if _, ok := DateFormats[value]; !ok {
+		return &FieldError{
+			FieldName: attribute,
+			Message:   "The selected {attribute} is invalid.",
+			Placeholders: List{
+				{"attribute", attribute},
+			},
+		}
+	}
+	return nil
+}
+
 func ValidateIP(attribute, value string) *FieldError {
 	if net.ParseIP(value) == nil {
 		return &FieldError{
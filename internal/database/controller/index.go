@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+)
+
+const MIGRATION_PATH = "internal/database/migration"
+
+// Indexes compara los indices declarados en los modelos con los de la base de datos.
+// ?action=plan (predeterminado) solo muestra el plan
+// ?action=apply ejecuta el plan
+// ?action=generate crea un archivo de migracion con el plan y lo registra en run_migration.go
+func Indexes(ctx *app.HttpContext) {
+
+	if !app.Env.DB_MIGRATION_ENABLE {
+		ctx.ResponseError(app.Errors.Forbiddenf("Migration disabled"))
+		return
+	}
+
+	plan, err := app.DiffIndexes()
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	for _, change := range plan {
+		app.PrintInfo(":action index :collection :name (:reason)",
+			app.E("action", change.Action),
+			app.E("collection", change.Collection),
+			app.E("name", change.Index.IndexName()),
+			app.E("reason", change.Reason),
+		)
+	}
+	if len(plan) == 0 {
+		app.PrintInfo("Indexes are up to date")
+	}
+
+	switch ctx.Request.URL.Query().Get("action") {
+	case "", "plan":
+		ctx.ResponseOk(plan)
+	case "apply":
+		if err := app.ApplyIndexes(plan); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		ctx.ResponseOk(plan)
+	case "generate":
+		if len(plan) == 0 {
+			ctx.ResponseOk(plan)
+			return
+		}
+		fileName, err := generateIndexMigration(plan)
+		if err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		app.PrintInfo("Generated migration :file", app.E("file", fileName))
+		ctx.ResponseCreated(map[string]string{"file": fileName})
+	default:
+		ctx.ResponseError(app.Errors.BadRequestf("The action must be plan, apply or generate"))
+	}
+}
+
+// generateIndexMigration escribe la migracion con el plan y la agrega al final de migration.Run
+func generateIndexMigration(plan []app.IndexChange) (string, app.Error) {
+	now := time.Now()
+	suffix := now.Format("20060102_150405")
+	funcName := "SyncIndexes" + suffix
+
+	up := []string{}
+	down := []string{}
+	for _, change := range plan {
+		if change.Action == app.INDEX_DROP {
+			up = append(up, fmt.Sprintf("DropIndex(%q, %q)", change.Collection, change.Index.IndexName()))
+			down = append(down, fmt.Sprintf("CreateModelIndex(%q, %s)", change.Collection, indexSource(change.Index)))
+		} else {
+			up = append(up, fmt.Sprintf("CreateModelIndex(%q, %s)", change.Collection, indexSource(change.Index)))
+			down = append(down, fmt.Sprintf("DropIndex(%q, %q)", change.Collection, change.Index.IndexName()))
+		}
+	}
+	// el down se ejecuta en orden inverso
+	for i, j := 0, len(down)-1; i < j; i, j = i+1, j-1 {
+		down[i], down[j] = down[j], down[i]
+	}
+
+	source := fmt.Sprintf(`package migration
+
+import (
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func %sUp() {
+	%s
+}
+
+func %sDown() {
+	%s
+}
+`, funcName, strings.Join(up, "\n\t"), funcName, strings.Join(down, "\n\t"))
+
+	formatted, er := format.Source([]byte(source))
+	if er != nil {
+		return "", app.Errors.InternalServerError(er)
+	}
+
+	fileName := filepath.Join(MIGRATION_PATH, now.Format("20060102_150405")+"_sync_indexes.go")
+	if er := os.WriteFile(fileName, formatted, 0644); er != nil {
+		return "", app.Errors.InternalServerError(er)
+	}
+
+	// se registra debajo del comentario de run_migration.go
+	runFile := filepath.Join(MIGRATION_PATH, "run_migration.go")
+	content, er := os.ReadFile(runFile)
+	if er != nil {
+		return "", app.Errors.InternalServerError(er)
+	}
+	marker := strings.Index(string(content), "// registre aca abajo sus funciones de migracion")
+	end := -1
+	if marker >= 0 {
+		end = strings.Index(string(content)[marker:], "\n}\n")
+	}
+	if end < 0 {
+		app.PrintWarning("Register the migration manually in :file", app.E("file", runFile))
+		return fileName, nil
+	}
+	end += marker + 1
+	line := fmt.Sprintf("\tadd(\"sync indexes %s\", %sUp, %sDown)\n", suffix, funcName, funcName)
+	content = []byte(string(content)[:end] + line + string(content)[end:])
+	if er := os.WriteFile(runFile, content, 0644); er != nil {
+		return "", app.Errors.InternalServerError(er)
+	}
+	return fileName, nil
+}
+
+// indexSource escribe el indice como codigo go
+func indexSource(index app.ModelIndex) string {
+	keys := []string{}
+	for _, key := range index.Keys {
+		if s, ok := key.Value.(string); ok {
+			keys = append(keys, fmt.Sprintf("{Key: %q, Value: %q}", key.Key, s))
+		} else {
+			keys = append(keys, fmt.Sprintf("{Key: %q, Value: %v}", key.Key, key.Value))
+		}
+	}
+	source := fmt.Sprintf("app.ModelIndex{Name: %q, Keys: bson.D{%s}, Unique: %t, Sparse: %t}",
+		index.IndexName(), strings.Join(keys, ", "), index.Unique, index.Sparse)
+	if index.TTL != nil {
+		source += fmt.Sprintf(".SetTTL(%d)", *index.TTL)
+	}
+	return source
+}
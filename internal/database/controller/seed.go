@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -136,3 +137,30 @@ func SeedTracker(ctx *app.HttpContext) {
 
 	ctx.ResponseOk(records)
 }
+
+// WorldImport actualiza paises, estados y ciudades desde los JSON del dataset sin cambiar los _id,
+// con ?dry_run=true solo responde lo que se insertaria y actualizaria
+func WorldImport(ctx *app.HttpContext) {
+	if !app.Env.DB_MIGRATION_ENABLE {
+		ctx.ResponseError(app.Errors.Forbiddenf("Migration disabled"))
+		app.PrintError("Migration disabled")
+		return
+	}
+
+	dryRun := false
+	if value := ctx.Request.URL.Query().Get("dry_run"); value != "" {
+		var er error
+		if dryRun, er = strconv.ParseBool(value); er != nil {
+			ctx.ResponseError(app.Errors.BadRequestf("The dry_run must be true or false"))
+			return
+		}
+	}
+
+	result, err := seed.WorldImport(dryRun)
+	if err != nil {
+		app.PrintError("Fail to import world :error", app.E("error", err.Error()))
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(result)
+}
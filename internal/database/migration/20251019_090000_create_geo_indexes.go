@@ -0,0 +1,16 @@
+package migration
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+// indices 2dsphere para las busquedas por cercania ($geoNear, $near)
+func GeoIndexesUp() {
+	CreateModelIndex("countries", app.GeoIndex("location"))
+	CreateModelIndex("states", app.GeoIndex("location"))
+	CreateModelIndex("cities", app.GeoIndex("location"))
+}
+
+func GeoIndexesDown() {
+	DropIndex("countries", app.GeoIndex("location").IndexName())
+	DropIndex("states", app.GeoIndex("location").IndexName())
+	DropIndex("cities", app.GeoIndex("location").IndexName())
+}
@@ -0,0 +1,15 @@
+package migration
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+// jti de los JWT revocados, el indice TTL los elimina cuando vence el token
+func RevokedTokensUp() {
+	CreateCollection("revoked_tokens", func(collection string) {
+		CreateUniqueIndex(collection, 1, "jti")
+		CreateModelIndex(collection, app.Index(1, "expires_at").SetTTL(0))
+	})
+}
+
+func RevokedTokensDown() {
+	DropCollection("revoked_tokens")
+}
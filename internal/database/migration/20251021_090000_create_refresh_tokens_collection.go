@@ -0,0 +1,17 @@
+package migration
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+// refresh tokens guardados con hash, el indice TTL los elimina cuando vencen
+func RefreshTokensUp() {
+	CreateCollection("refresh_tokens", func(collection string) {
+		CreateUniqueIndex(collection, 1, "token_hash")
+		CreateIndex(collection, 1, "family_id")
+		CreateIndex(collection, 1, "user_id")
+		CreateModelIndex(collection, app.Index(1, "expires_at").SetTTL(0))
+	})
+}
+
+func RefreshTokensDown() {
+	DropCollection("refresh_tokens")
+}
@@ -0,0 +1,31 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// los tokens pasan a guardarse con prefijo y hash, los que estaban en claro no se pueden convertir
+// porque el cliente no tiene el prefijo, se eliminan y los usuarios deben iniciar sesion de nuevo
+func HashAccessTokensUp() {
+	deleteAccessTokens()
+	DropIndex("access_tokens", app.UniqueIndex(1, "token").IndexName())
+	CreateUniqueIndex("access_tokens", 1, "prefix")
+}
+
+func HashAccessTokensDown() {
+	deleteAccessTokens()
+	DropIndex("access_tokens", app.UniqueIndex(1, "prefix").IndexName())
+	CreateUniqueIndex("access_tokens", 1, "token")
+}
+
+func deleteAccessTokens() {
+	result, er := app.DB.Collection("access_tokens").DeleteMany(context.TODO(), bson.D{})
+	if er != nil {
+		app.PrintError("Failed to delete access tokens :error", app.E("error", er.Error()))
+		panic(er.Error())
+	}
+	app.PrintInfo("Deleted :count access tokens", app.E("count", result.DeletedCount))
+}
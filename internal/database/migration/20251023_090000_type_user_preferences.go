@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// profile.preferences paso de un mapa libre a model.Preferences, al decodificar se pierden las claves que no conoce
+// y el siguiente guardado las borra. Se copia el mapa viejo en preferences_legacy (en la raiz del usuario
+// para que el $set del modelo no lo pise) y se dejan solo las claves con el tipo correcto.
+// Las notificaciones que no existian quedan con los valores por defecto de DefaultPreferences
+func TypeUserPreferencesUp() {
+	updateUsers("Typed preferences of :count users",
+		bson.D{
+			{Key: "profile.preferences", Value: bson.D{{Key: "$type", Value: "object"}}},
+			{Key: "preferences_legacy", Value: bson.D{{Key: "$exists", Value: false}}},
+		},
+		mongo.Pipeline{{{Key: "$set", Value: bson.D{
+			{Key: "preferences_legacy", Value: "$profile.preferences"},
+			{Key: "profile.preferences", Value: bson.D{
+				{Key: "timezone", Value: typedOrRemove("$profile.preferences.timezone", "string")},
+				{Key: "locale", Value: typedOrRemove("$profile.preferences.locale", "string")},
+				{Key: "date_format", Value: typedOrRemove("$profile.preferences.date_format", "string")},
+				{Key: "notifications", Value: bson.D{
+					{Key: "email", Value: typedOr("$profile.preferences.notifications.email", "bool", true)},
+					{Key: "push", Value: typedOr("$profile.preferences.notifications.push", "bool", true)},
+					{Key: "marketing", Value: typedOr("$profile.preferences.notifications.marketing", "bool", false)},
+				}},
+			}},
+		}}}},
+	)
+}
+
+// TypeUserPreferencesDown devuelve el mapa original
+func TypeUserPreferencesDown() {
+	updateUsers("Restored preferences of :count users",
+		bson.D{{Key: "preferences_legacy", Value: bson.D{{Key: "$exists", Value: true}}}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.D{{Key: "profile.preferences", Value: "$preferences_legacy"}}}},
+			{{Key: "$unset", Value: "preferences_legacy"}},
+		},
+	)
+}
+
+func updateUsers(message string, filter bson.D, update mongo.Pipeline) {
+	result, er := app.DB.Collection("users").UpdateMany(context.TODO(), filter, update)
+	if er != nil {
+		app.PrintError("Failed to update users :error", app.E("error", er.Error()))
+		panic(er.Error())
+	}
+	app.PrintInfo(message, app.E("count", result.ModifiedCount))
+}
+
+// typedOr es el valor del campo si es del tipo bson indicado, si no el valor por defecto
+func typedOr(field string, bsonType string, value any) bson.D {
+	return bson.D{{Key: "$cond", Value: bson.A{
+		bson.D{{Key: "$eq", Value: bson.A{bson.D{{Key: "$type", Value: field}}, bsonType}}},
+		field,
+		value,
+	}}}
+}
+
+// typedOrRemove igual que typedOr pero si no es del tipo el campo no se escribe
+func typedOrRemove(field string, bsonType string) bson.D {
+	return typedOr(field, bsonType, "$$REMOVE")
+}
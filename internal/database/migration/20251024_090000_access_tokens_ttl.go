@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"context"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// mongo elimina los access tokens vencidos. Los tokens de API que no vencen se guardaban con expires_at en cero
+// y el TTL los eliminaria, se les quita el campo antes de crear el indice.
+// Las sesiones se listan por la familia de refresh tokens, se indexa access_token_id para buscarla desde el token
+func AccessTokensTTLUp() {
+	result, er := app.DB.Collection("access_tokens").UpdateMany(context.TODO(),
+		bson.D{{Key: "expires_at", Value: bson.D{{Key: "$lt", Value: time.Unix(0, 0)}}}},
+		bson.D{{Key: "$unset", Value: bson.D{{Key: "expires_at", Value: nil}}}},
+	)
+	if er != nil {
+		app.PrintError("Failed to unset expires_at of access tokens :error", app.E("error", er.Error()))
+		panic(er.Error())
+	}
+	app.PrintInfo("Unset expires_at of :count access tokens", app.E("count", result.ModifiedCount))
+
+	CreateModelIndex("access_tokens", app.Index(1, "expires_at").SetTTL(0))
+	CreateIndex("refresh_tokens", 1, "access_token_id")
+}
+
+func AccessTokensTTLDown() {
+	DropIndex("refresh_tokens", app.Index(1, "access_token_id").IndexName())
+	DropIndex("access_tokens", app.Index(1, "expires_at").IndexName())
+}
@@ -0,0 +1,40 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Country y SystemLog tenian el ID con el tag bson "id", al leerlos el ID quedaba vacio y al actualizarlos
+// con $set del modelo se escribia un campo id aparte del _id. Ahora el tag es "_id", se quita el campo sobrante
+func ModelsObjectIDUp() {
+	for _, collection := range []string{"countries", "system_logs"} {
+		result, er := app.DB.Collection(collection).UpdateMany(context.TODO(),
+			bson.D{{Key: "id", Value: bson.D{{Key: "$type", Value: "objectId"}}}},
+			bson.D{{Key: "$unset", Value: bson.D{{Key: "id", Value: nil}}}},
+		)
+		if er != nil {
+			app.PrintError("Failed to unset id of :collection :error", app.E("collection", collection), app.E("error", er.Error()))
+			panic(er.Error())
+		}
+		app.PrintInfo("Unset id of :count :collection", app.E("count", result.ModifiedCount), app.E("collection", collection))
+	}
+}
+
+// ModelsObjectIDDown vuelve a copiar el _id en id para el tag anterior
+func ModelsObjectIDDown() {
+	for _, collection := range []string{"countries", "system_logs"} {
+		result, er := app.DB.Collection(collection).UpdateMany(context.TODO(),
+			bson.D{{Key: "id", Value: bson.D{{Key: "$exists", Value: false}}}},
+			mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "id", Value: "$_id"}}}}},
+		)
+		if er != nil {
+			app.PrintError("Failed to set id of :collection :error", app.E("collection", collection), app.E("error", er.Error()))
+			panic(er.Error())
+		}
+		app.PrintInfo("Set id of :count :collection", app.E("count", result.ModifiedCount), app.E("collection", collection))
+	}
+}
@@ -27,6 +27,13 @@ func Run() {
 	add("create cities", CitiesUp, CitiesDown)
 
 	// registre aca abajo sus funciones de migracion
+	add("create geo indexes", GeoIndexesUp, GeoIndexesDown)
+	add("create revoked_tokens", RevokedTokensUp, RevokedTokensDown)
+	add("create refresh_tokens", RefreshTokensUp, RefreshTokensDown)
+	add("hash access_tokens", HashAccessTokensUp, HashAccessTokensDown)
+	add("type user preferences", TypeUserPreferencesUp, TypeUserPreferencesDown)
+	add("access_tokens ttl", AccessTokensTTLUp, AccessTokensTTLDown)
+	add("models object id", ModelsObjectIDUp, ModelsObjectIDDown)
 
 }
 
@@ -98,6 +105,16 @@ func CreateIndexWithOptions(collection string, keys bson.D, options *options.Ind
 	app.PrintInfo("Created index :collection :name", app.E("collection", collection), app.E("name", name))
 }
 
+// CreateModelIndex crea un indice declarado con app.Index, app.UniqueIndex o app.TextIndex
+func CreateModelIndex(collection string, index app.ModelIndex) {
+	name, er := app.DB.Collection(collection).Indexes().CreateOne(context.TODO(), index.IndexModel())
+	if er != nil {
+		app.PrintError("Failed to create index :collection :error ", app.E("collection", collection), app.E("error", er.Error()))
+		panic(er.Error())
+	}
+	app.PrintInfo("Created index :collection :name", app.E("collection", collection), app.E("name", name))
+}
+
 func CreateCollection(collection string, fun func(string), opts ...options.Lister[options.CreateCollectionOptions]) {
 	er := app.DB.CreateCollection(context.TODO(), collection, opts...)
 	if er != nil {
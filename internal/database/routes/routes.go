@@ -9,11 +9,13 @@ import (
 func Migration(r *app.Routes) {
 	r.Prefix("db", func() {
 		r.Get("/seed", controller.Seed)
+		r.Get("/seed/world/import", controller.WorldImport)
 		r.Get("/migrate", controller.Migrare)
 		r.Get("/migrate/fresh", controller.Fresh)
 		r.Get("/migrate/reset", controller.Reset)
 		r.Get("/migrate/refresh", controller.Refresh)
 		r.Get("/migrate/rollback", controller.Rollback)
+		r.Get("/migrate/indexes", controller.Indexes)
 
 	}, middleware.OnlyLocalhost)
 }
@@ -18,156 +18,157 @@ func World() {
 	subregions := loadSubRegions()
 	seedCountries := loadCountries()
 
-	var totalCountries int
+	countries := []app.BulkOperation{}
 	for _, seedCountry := range seedCountries {
-		country := model.NewCountry()
-		country.Name = seedCountry.Name
-		country.Iso3 = seedCountry.Iso3
-		country.Iso2 = seedCountry.Iso2
-		country.NumericCode = seedCountry.NumericCode
-		country.PhoneCode = seedCountry.Phonecode
-		country.Capital = seedCountry.Capital
-		country.Currency = seedCountry.Currency
-		country.CurrencyName = seedCountry.CurrencyName
-		country.CurrencySymbol = seedCountry.CurrencySymbol
-		country.TLD = seedCountry.TLD
-		country.Native = seedCountry.Native
-		country.Nationality = seedCountry.Nationality
-		country.Timezones = seedCountry.Timezones
-		country.Translations = seedCountry.Translations
-		country.Emoji = seedCountry.Emoji
-		country.EmojiU = seedCountry.EmojiU
-
-		for _, region := range regions {
-			if region.ID == seedCountry.RegionID {
-				country.Region = region
-				break
-			}
-		}
-		for _, subregion := range subregions {
-			if subregion.ID == seedCountry.SubregionID {
-				country.Subregion = subregion
-				break
-			}
-		}
-		var latitude, longitud float64
-		var er error
-		latitude, er = strconv.ParseFloat(seedCountry.Latitude, 64)
-		if er != nil {
-			latitude = 0
-		}
-		longitud, er = strconv.ParseFloat(seedCountry.Longitude, 64)
-		if er != nil {
-			longitud = 0
-		}
-		country.Location = *app.NewGeoPoint(latitude, longitud)
-
-		if err := country.Create(); err != nil {
-			app.PrintError("Fail to create country :error", app.E("error", err.Error()))
-			panic(err.Error())
-		}
+		country := countryFromSeed(seedCountry, regions, subregions)
+		country.SetID(bson.NewObjectID())
 		countriesIDs[seedCountry.ID] = country.GetID()
-		totalCountries++
+		countries = append(countries, app.BulkInsert(country))
 	}
-	app.PrintInfo("Seeded countries :total wait seeding states", app.E("total", totalCountries))
+	countryResult, err := model.NewCountry().BulkWrite(countries)
+	if err != nil {
+		app.PrintError("Fail to create countries :error", app.E("error", err.Error()), app.E("processed", countryResult.Processed))
+		panic(err.Error())
+	}
+	app.PrintInfo("Seeded countries :total wait seeding states", app.E("total", countryResult.Inserted))
 
 	seedStates := loadStates()
-	var totalStates int
+	// los ids se asignan antes de insertar para poder resolver el parent_id en una sola pasada
 	for _, seedState := range seedStates {
-		state := model.NewState()
-		state.Name = seedState.Name
-		state.CountryID = countriesIDs[seedState.CountryID]
-		state.CountryCode = seedState.CountryCode
-		state.CountryName = seedState.CountryName
-		state.Iso2 = seedState.Iso2
-		state.Iso3166_2 = seedState.Iso3166_2
-		state.FipsCode = seedState.FipsCode
-		state.Type = seedState.Type
-		//state.ParentID = seedState.ParentID
-		state.Timezone = seedState.Timezone
-
-		var level int
-		if seedState.Level != "" {
-			level, _ = strconv.Atoi(seedState.Level)
-		}
-		if level == 0 {
-			level = 1
-		}
-		state.Level = level
-
-		var latitude, longitud float64
-		var er error
-		latitude, er = strconv.ParseFloat(seedState.Latitude, 64)
-		if er != nil {
-			latitude = 0
-		}
-		longitud, er = strconv.ParseFloat(seedState.Longitude, 64)
-		if er != nil {
-			longitud = 0
-		}
-		state.Location = *app.NewGeoPoint(latitude, longitud)
-
-		if err := state.Create(); err != nil {
-			app.PrintError("Fail to create state :error", app.E("error", err.Error()))
-			panic(err.Error())
-		}
-		statesIDs[seedState.ID] = state.GetID()
-		totalStates++
+		statesIDs[seedState.ID] = bson.NewObjectID()
 	}
+	states := []app.BulkOperation{}
 	for _, seedState := range seedStates {
-		if seedState.ParentID != "" {
-			state := model.NewState()
-			state.FindByID(statesIDs[seedState.ID])
-			var parentID int
-			var er error
-			parentID, er = strconv.Atoi(seedState.ParentID)
-			if er != nil {
-				app.PrintError("Fail to add parent state :error", app.E("error", er.Error()))
-				panic(er.Error())
-			}
+		state := stateFromSeed(seedState)
+		state.SetID(statesIDs[seedState.ID])
+		state.CountryID = countriesIDs[seedState.CountryID]
+		if parentID := seedState.parentID(); parentID != 0 {
 			state.ParentID = statesIDs[parentID]
-			if err := state.Update(); err != nil {
-				app.PrintError("Fail to add parent state :error", app.E("error", err.Error()))
-				panic(err.Error())
-			}
 		}
+		states = append(states, app.BulkInsert(state))
 	}
-	app.PrintInfo("Seeded states :total wait seeding cities", app.E("total", totalStates))
+	stateResult, err := model.NewState().BulkWrite(states)
+	if err != nil {
+		app.PrintError("Fail to create states :error", app.E("error", err.Error()), app.E("processed", stateResult.Processed))
+		panic(err.Error())
+	}
+	app.PrintInfo("Seeded states :total wait seeding cities", app.E("total", stateResult.Inserted))
 
 	seedCities := loadCities()
-	allcityes := []*model.City{}
+	cities := []app.BulkOperation{}
 	for _, seedCity := range seedCities {
-		city := model.NewCity()
-		city.Name = seedCity.Name
+		city := cityFromSeed(seedCity)
 		city.StateID = statesIDs[seedCity.StateID]
-		city.StateCode = seedCity.StateCode
-		city.StateName = seedCity.StateName
 		city.CountryID = countriesIDs[seedCity.CountryID]
-		city.CountryCode = seedCity.CountryCode
-		city.CountryName = seedCity.CountryName
-		city.Timezone = seedCity.Timezone
-
-		var latitude, longitud float64
-		var er error
-		latitude, er = strconv.ParseFloat(seedCity.Latitude, 64)
-		if er != nil {
-			latitude = 0
+		cities = append(cities, app.BulkInsert(city))
+	}
+	app.PrintInfo("file cities ready :total", app.E("total", len(cities)))
+	cityResult, err := model.NewCity().BulkWrite(cities, &app.BulkOptions{Ordered: false, BatchSize: 5000})
+	if err != nil {
+		app.PrintError("Fail to create cities :error", app.E("error", err.Error()), app.E("processed", cityResult.Processed))
+		panic(err.Error())
+	}
+	app.PrintInfo("Finish seed cities :total", app.E("total", cityResult.Inserted))
+
+}
+
+// constructores compartidos con WorldImport, los ids de las relaciones los asigna quien los llama
+
+func countryFromSeed(seedCountry CountrySeed, regions []model.CountryRegion, subregions []model.CountrySubRegion) *model.Country {
+	country := model.NewCountry()
+	country.Name = seedCountry.Name
+	country.Iso3 = seedCountry.Iso3
+	country.Iso2 = seedCountry.Iso2
+	country.NumericCode = seedCountry.NumericCode
+	country.PhoneCode = seedCountry.Phonecode
+	country.Capital = seedCountry.Capital
+	country.Currency = seedCountry.Currency
+	country.CurrencyName = seedCountry.CurrencyName
+	country.CurrencySymbol = seedCountry.CurrencySymbol
+	country.TLD = seedCountry.TLD
+	country.Native = seedCountry.Native
+	country.Nationality = seedCountry.Nationality
+	country.Timezones = seedCountry.Timezones
+	country.Translations = seedCountry.Translations
+	country.Emoji = seedCountry.Emoji
+	country.EmojiU = seedCountry.EmojiU
+
+	for _, region := range regions {
+		if region.ID == seedCountry.RegionID {
+			country.Region = region
+			break
 		}
-		longitud, er = strconv.ParseFloat(seedCity.Longitude, 64)
-		if er != nil {
-			longitud = 0
+	}
+	for _, subregion := range subregions {
+		if subregion.ID == seedCountry.SubregionID {
+			country.Subregion = subregion
+			break
 		}
-		city.Location = *app.NewGeoPoint(latitude, longitud)
-		allcityes = append(allcityes, city)
 	}
-	app.PrintInfo("file cities ready :total", app.E("total", len(allcityes)))
+	country.Location = seedGeoPoint(seedCountry.Latitude, seedCountry.Longitude)
+	return country
+}
+
+func stateFromSeed(seedState StateSeed) *model.State {
+	state := model.NewState()
+	state.Name = seedState.Name
+	state.CountryCode = seedState.CountryCode
+	state.CountryName = seedState.CountryName
+	state.Iso2 = seedState.Iso2
+	state.Iso3166_2 = seedState.Iso3166_2
+	state.FipsCode = seedState.FipsCode
+	state.Type = seedState.Type
+	state.Timezone = seedState.Timezone
+
+	var level int
+	if seedState.Level != "" {
+		level, _ = strconv.Atoi(seedState.Level)
+	}
+	if level == 0 {
+		level = 1
+	}
+	state.Level = level
+	state.Location = seedGeoPoint(seedState.Latitude, seedState.Longitude)
+	return state
+}
+
+func cityFromSeed(seedCity CitySeed) *model.City {
 	city := model.NewCity()
-	if err := city.CreateMany(allcityes); err != nil {
-		app.PrintError("Fail to create cities :error", app.E("error", err.Error()))
-		panic(err.Error())
+	city.Name = seedCity.Name
+	city.StateCode = seedCity.StateCode
+	city.StateName = seedCity.StateName
+	city.CountryCode = seedCity.CountryCode
+	city.CountryName = seedCity.CountryName
+	city.Timezone = seedCity.Timezone
+	city.WikiDataID = seedCity.WikiDataID
+	city.Location = seedGeoPoint(seedCity.Latitude, seedCity.Longitude)
+	return city
+}
+
+// seedGeoPoint las coordenadas vienen como string en el JSON, si no se pueden leer quedan en 0
+func seedGeoPoint(latitude string, longitude string) app.GeoPoint {
+	lat, er := strconv.ParseFloat(latitude, 64)
+	if er != nil {
+		lat = 0
+	}
+	lng, er := strconv.ParseFloat(longitude, 64)
+	if er != nil {
+		lng = 0
 	}
-	app.PrintInfo("Finish seed cities :total", app.E("total", len(allcityes)))
+	return *app.NewGeoPoint(lat, lng)
+}
 
+// parentID el id del estado padre en el JSON, 0 si no tiene
+func (s StateSeed) parentID() int {
+	if s.ParentID == "" {
+		return 0
+	}
+	parentID, er := strconv.Atoi(s.ParentID)
+	if er != nil {
+		app.PrintError("Fail to add parent state :error", app.E("error", er.Error()))
+		panic(er.Error())
+	}
+	return parentID
 }
 
 func loadRegions() []model.CountryRegion {
@@ -0,0 +1,299 @@
+package seed
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// WorldImportResult es el resumen de WorldImport por coleccion
+type WorldImportResult struct {
+	DryRun    bool        `json:"dry_run"`
+	Countries ImportCount `json:"countries"`
+	States    ImportCount `json:"states"`
+	Cities    ImportCount `json:"cities"`
+}
+
+type ImportCount struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// WorldImport vuelve a leer los JSON del dataset y los compara con mongo:
+// paises por iso2 (o iso3), estados por pais e iso2 (o nombre) y ciudades por wikiDataId (o estado y nombre).
+// Los existentes conservan su _id para no romper las referencias (ej: profile.city_id),
+// solo se les hace $set de los campos que cambiaron. Los eliminados con deleted_at se actualizan pero no se restauran.
+// Con dryRun solo se cuenta lo que se haria.
+func WorldImport(dryRun bool) (*WorldImportResult, app.Error) {
+	result := &WorldImportResult{DryRun: dryRun}
+
+	countriesIDs, err := importCountries(result, dryRun)
+	if err != nil {
+		return result, err
+	}
+	statesIDs, err := importStates(result, dryRun, countriesIDs)
+	if err != nil {
+		return result, err
+	}
+	if err := importCities(result, dryRun, countriesIDs, statesIDs); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// importCountries retorna el _id de cada pais por el id del JSON
+func importCountries(result *WorldImportResult, dryRun bool) (map[int]bson.ObjectID, app.Error) {
+	existing := []*model.Country{}
+	if err := model.NewCountry().Find(&existing, bson.D{}); err != nil {
+		return nil, err
+	}
+	byIso2 := map[string]*model.Country{}
+	byIso3 := map[string]*model.Country{}
+	for _, c := range existing {
+		if c.Iso2 != "" {
+			byIso2[c.Iso2] = c
+		}
+		if c.Iso3 != "" {
+			byIso3[c.Iso3] = c
+		}
+	}
+
+	regions := loadRegions()
+	subregions := loadSubRegions()
+	countriesIDs := map[int]bson.ObjectID{}
+	operations := []app.BulkOperation{}
+	for _, seedCountry := range loadCountries() {
+		country := countryFromSeed(seedCountry, regions, subregions)
+		current, ok := byIso2[seedCountry.Iso2]
+		if !ok || seedCountry.Iso2 == "" {
+			current, ok = byIso3[seedCountry.Iso3]
+		}
+		if !ok {
+			country.SetID(bson.NewObjectID())
+			countriesIDs[seedCountry.ID] = country.GetID()
+			operations = append(operations, app.BulkInsert(country))
+			result.Countries.Inserted++
+			continue
+		}
+
+		countriesIDs[seedCountry.ID] = current.ID
+		operation, err := importUpdate(current, country)
+		if err != nil {
+			return nil, err
+		}
+		if operation == nil {
+			result.Countries.Unchanged++
+			continue
+		}
+		operations = append(operations, *operation)
+		result.Countries.Updated++
+	}
+
+	if err := importWrite(model.NewCountry(), operations, dryRun); err != nil {
+		return nil, err
+	}
+	app.PrintInfo("Imported countries :result", app.E("result", result.Countries))
+	return countriesIDs, nil
+}
+
+// importStates retorna el _id de cada estado por el id del JSON
+func importStates(result *WorldImportResult, dryRun bool, countriesIDs map[int]bson.ObjectID) (map[int]bson.ObjectID, app.Error) {
+	existing := []*model.State{}
+	if err := model.NewState().Find(&existing, bson.D{}); err != nil {
+		return nil, err
+	}
+	byIso2 := map[string]*model.State{}
+	byName := map[string]*model.State{}
+	for _, s := range existing {
+		if s.Iso2 != "" {
+			byIso2[s.CountryID.Hex()+"|"+s.Iso2] = s
+		}
+		byName[s.CountryID.Hex()+"|"+s.Name] = s
+	}
+
+	// primero se resuelven los ids de todos para poder asignar el parent_id
+	seedStates := loadStates()
+	statesIDs := map[int]bson.ObjectID{}
+	currents := map[int]*model.State{}
+	for _, seedState := range seedStates {
+		countryID := countriesIDs[seedState.CountryID].Hex()
+		current, ok := byIso2[countryID+"|"+seedState.Iso2]
+		if !ok || seedState.Iso2 == "" {
+			current, ok = byName[countryID+"|"+seedState.Name]
+		}
+		if ok {
+			currents[seedState.ID] = current
+			statesIDs[seedState.ID] = current.ID
+		} else {
+			statesIDs[seedState.ID] = bson.NewObjectID()
+		}
+	}
+
+	operations := []app.BulkOperation{}
+	for _, seedState := range seedStates {
+		state := stateFromSeed(seedState)
+		state.CountryID = countriesIDs[seedState.CountryID]
+		if parentID := seedState.parentID(); parentID != 0 {
+			state.ParentID = statesIDs[parentID]
+		}
+
+		current, ok := currents[seedState.ID]
+		if !ok {
+			state.SetID(statesIDs[seedState.ID])
+			operations = append(operations, app.BulkInsert(state))
+			result.States.Inserted++
+			continue
+		}
+		operation, err := importUpdate(current, state)
+		if err != nil {
+			return nil, err
+		}
+		if operation == nil {
+			result.States.Unchanged++
+			continue
+		}
+		operations = append(operations, *operation)
+		result.States.Updated++
+	}
+
+	if err := importWrite(model.NewState(), operations, dryRun); err != nil {
+		return nil, err
+	}
+	app.PrintInfo("Imported states :result", app.E("result", result.States))
+	return statesIDs, nil
+}
+
+func importCities(result *WorldImportResult, dryRun bool, countriesIDs map[int]bson.ObjectID, statesIDs map[int]bson.ObjectID) app.Error {
+	existing := []*model.City{}
+	if err := model.NewCity().Find(&existing, bson.D{}); err != nil {
+		return err
+	}
+	byWikiData := map[string]*model.City{}
+	byName := map[string]*model.City{}
+	for _, c := range existing {
+		if c.WikiDataID != "" {
+			byWikiData[c.WikiDataID] = c
+		}
+		byName[c.StateID.Hex()+"|"+c.Name] = c
+	}
+
+	operations := []app.BulkOperation{}
+	for _, seedCity := range loadCities() {
+		city := cityFromSeed(seedCity)
+		city.StateID = statesIDs[seedCity.StateID]
+		city.CountryID = countriesIDs[seedCity.CountryID]
+
+		current, ok := byWikiData[seedCity.WikiDataID]
+		if !ok || seedCity.WikiDataID == "" {
+			current, ok = byName[city.StateID.Hex()+"|"+seedCity.Name]
+		}
+		if !ok {
+			operations = append(operations, app.BulkInsert(city))
+			result.Cities.Inserted++
+			continue
+		}
+		operation, err := importUpdate(current, city)
+		if err != nil {
+			return err
+		}
+		if operation == nil {
+			result.Cities.Unchanged++
+			continue
+		}
+		operations = append(operations, *operation)
+		result.Cities.Updated++
+	}
+
+	if err := importWrite(model.NewCity(), operations, dryRun); err != nil {
+		return err
+	}
+	app.PrintInfo("Imported cities :result", app.E("result", result.Cities))
+	return nil
+}
+
+// importUpdate compara el documento existente con el del dataset y arma el $set de los campos distintos,
+// retorna nil si no hay cambios. Los campos que el dataset no trae no se borran.
+func importUpdate(current app.Model, imported app.Model) (*app.BulkOperation, app.Error) {
+	currentRaw, er := bson.Marshal(current)
+	if er != nil {
+		return nil, app.Errors.InternalServerError(er)
+	}
+	importedRaw, er := bson.Marshal(imported)
+	if er != nil {
+		return nil, app.Errors.InternalServerError(er)
+	}
+	elements, er := bson.Raw(importedRaw).Elements()
+	if er != nil {
+		return nil, app.Errors.InternalServerError(er)
+	}
+
+	changes := []bson.E{}
+	for _, element := range elements {
+		key := element.Key()
+		switch key {
+		case "_id", "created_at", "updated_at", "deleted_at":
+			continue
+		}
+		value := element.Value()
+		if old, er := bson.Raw(currentRaw).LookupErr(key); er == nil && sameValue(old, value) {
+			continue
+		}
+		changes = append(changes, Element(key, value))
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	changes = append(changes, Element("updated_at", time.Now()))
+	operation := app.BulkUpdateOne(Filter(Where("_id", Eq(current.GetID()))), Set(changes...))
+	return &operation, nil
+}
+
+func importWrite(m app.Model, operations []app.BulkOperation, dryRun bool) app.Error {
+	if dryRun || len(operations) == 0 {
+		return nil
+	}
+	odm := &app.Odm{Model: m}
+	if _, err := odm.BulkWrite(operations, &app.BulkOptions{Ordered: false, BatchSize: 5000}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// sameValue compara los valores bson, los documentos sin importar el orden de las claves
+// por que los mapas (ej: translations) no conservan el orden al codificarse
+func sameValue(a bson.RawValue, b bson.RawValue) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type != bson.TypeEmbeddedDocument && a.Type != bson.TypeArray {
+		return a.Equal(b)
+	}
+	aElements, er := bson.Raw(a.Value).Elements()
+	if er != nil {
+		return false
+	}
+	bElements, er := bson.Raw(b.Value).Elements()
+	if er != nil || len(aElements) != len(bElements) {
+		return false
+	}
+	for i, element := range aElements {
+		var other bson.RawValue
+		if a.Type == bson.TypeArray {
+			if element.Key() != bElements[i].Key() {
+				return false
+			}
+			other = bElements[i].Value()
+		} else if other, er = bson.Raw(b.Value).LookupErr(element.Key()); er != nil {
+			return false
+		}
+		if !sameValue(element.Value(), other) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// APIToken el token en claro solo viene en la respuesta de creacion, el prefijo sirve para reconocerlo
+type APIToken struct {
+	ID          bson.ObjectID `json:"id"`
+	Name        string        `json:"name"`
+	Prefix      string        `json:"prefix"`
+	Token       string        `json:"token,omitempty"`
+	Permissions []string      `json:"permissions"`
+	LastUsedAt  *time.Time    `json:"last_used_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	ExpiresAt   *time.Time    `json:"expires_at"` // null si no vence
+	ExpiresOn   string        `json:"expires_on"` // la fecha con el formato del usuario, vacio si no vence
+}
+
+// NewAPIToken las fechas van en la zona horaria del usuario
+func NewAPIToken(ctx *app.HttpContext, t *model.AccessToken) *APIToken {
+	token := &APIToken{
+		ID:          t.ID,
+		Name:        t.Name,
+		Prefix:      t.Prefix,
+		Token:       t.Token,
+		Permissions: t.Permissions,
+		LastUsedAt:  localTime(ctx, t.LastUsedAt),
+		CreatedAt:   ctx.LocalTime(t.CreatedAt),
+	}
+	if !t.ExpiresAt.IsZero() {
+		token.ExpiresAt = localTime(ctx, &t.ExpiresAt)
+		token.ExpiresOn = ctx.FormatDate(t.ExpiresAt)
+	}
+	return token
+}
+
+func NewAPITokens(ctx *app.HttpContext, tokens []*model.AccessToken) []*APIToken {
+	result := make([]*APIToken, 0, len(tokens))
+	for _, t := range tokens {
+		result = append(result, NewAPIToken(ctx, t))
+	}
+	return result
+}
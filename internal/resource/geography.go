@@ -0,0 +1,29 @@
+package resource
+
+import (
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+)
+
+// CityNear es la ciudad con la distancia en km al punto buscado
+type CityNear struct {
+	model.City `bson:",inline"`
+	Distance   float64 `bson:"distance" json:"distance"`
+}
+
+// CitySearch es la ciudad con la relevancia de la busqueda por nombre
+type CitySearch struct {
+	model.City `bson:",inline"`
+	Score      float64 `bson:"score" json:"score"`
+}
+
+// CountrySearch es el pais con la relevancia de la busqueda por nombre
+type CountrySearch struct {
+	model.Country `bson:",inline"`
+	Score         float64 `bson:"score" json:"score"`
+}
+
+// StateSearch es el estado con la relevancia de la busqueda por nombre
+type StateSearch struct {
+	model.State `bson:",inline"`
+	Score       float64 `bson:"score" json:"score"`
+}
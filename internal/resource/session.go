@@ -0,0 +1,65 @@
+package resource
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Session es la sesion de un dispositivo (una familia de refresh tokens), solo los datos para mostrar, nunca el token.
+// Las fechas van en la zona horaria del usuario y last_activity con su formato de fecha para mostrarla tal cual
+type Session struct {
+	ID           bson.ObjectID `json:"id"` // family_id, con este se cierra la sesion
+	DeviceName   string        `json:"device_name"`
+	IP           string        `json:"ip"`
+	UserAgent    string        `json:"user_agent"`
+	LastUsedAt   *time.Time    `json:"last_used_at"`
+	LastActivity string        `json:"last_activity"`
+	CreatedAt    time.Time     `json:"created_at"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+	Current      bool          `json:"current"` // es la sesion con la que se hizo la peticion
+}
+
+// NewSessions arma la sesion con el ultimo refresh token de la familia, si su access token sigue guardado
+// se toman de ahi la ip y la ultima actividad, si no la ultima actividad es el ultimo refresh
+func NewSessions(ctx *app.HttpContext, heads []*model.RefreshToken, tokens []*model.AccessToken, currentID bson.ObjectID) []*Session {
+	accessTokens := make(map[bson.ObjectID]*model.AccessToken, len(tokens))
+	for _, t := range tokens {
+		accessTokens[t.ID] = t
+	}
+
+	sessions := make([]*Session, 0, len(heads))
+	for _, head := range heads {
+		session := &Session{
+			ID:         head.FamilyID,
+			DeviceName: head.DeviceName,
+			IP:         head.IP,
+			UserAgent:  head.UserAgent,
+			CreatedAt:  ctx.LocalTime(head.FamilyID.Timestamp()), // la familia se crea en el login
+			ExpiresAt:  ctx.LocalTime(head.ExpiresAt),
+			Current:    head.AccessTokenID == currentID,
+		}
+		lastActivity := head.CreatedAt
+		if t, ok := accessTokens[head.AccessTokenID]; ok {
+			session.IP = t.IP
+			if t.LastUsedAt != nil {
+				lastActivity = *t.LastUsedAt
+			}
+		}
+		session.LastUsedAt = localTime(ctx, &lastActivity)
+		session.LastActivity = ctx.FormatTime(lastActivity)
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// localTime ctx.LocalTime para las fechas opcionales
+func localTime(ctx *app.HttpContext, t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := ctx.LocalTime(*t)
+	return &local
+}
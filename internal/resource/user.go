@@ -1,17 +1,47 @@
 package resource
 
 import (
+	"time"
+
 	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
 )
 
 type UserLogin struct {
-	AccessToken *model.AccessToken `json:"access_token"`
-	User        *model.User        `json:"user"`
+	AccessToken  *model.AccessToken `json:"access_token"`
+	RefreshToken string             `json:"refresh_token"`
+	User         *model.User        `json:"user"`
 }
 
-func NewUserLogin(u *model.User, t *model.AccessToken) *UserLogin {
+func NewUserLogin(u *model.User, t *model.AccessToken, r *model.RefreshToken) *UserLogin {
 	return &UserLogin{
-		User:        u,
-		AccessToken: t,
+		User:         u,
+		AccessToken:  t,
+		RefreshToken: r.Token,
+	}
+}
+
+// UserTwoFactorPending la contraseña es correcta pero falta el codigo, el token se envia a users/login/two-factor
+type UserTwoFactorPending struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	TwoFactorToken    string    `json:"two_factor_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+func NewUserTwoFactorPending(v *model.VerificationCode) *UserTwoFactorPending {
+	return &UserTwoFactorPending{
+		TwoFactorRequired: true,
+		TwoFactorToken:    v.UserID.Hex() + "." + v.Token,
+		ExpiresAt:         v.ExpiresAt,
 	}
 }
+
+// UserTwoFactorEnroll el secreto y el uri otpauth:// solo se muestran al iniciar la activacion
+type UserTwoFactorEnroll struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"` // para generar el QR
+}
+
+// UserRecoveryCodes los codigos en claro solo se muestran al activar el 2FA
+type UserRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
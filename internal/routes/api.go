@@ -11,6 +11,7 @@ func GetAll() *app.Routes {
 	r.Prefix("api", func() {
 		// aca todas las funciones que crean rutas de la api
 		user(r)
+		geography(r)
 
 	})
 	// rutas para las migraciones y seed
@@ -27,5 +28,8 @@ func GetAll() *app.Routes {
 	r.Get("users/reset-password/:id/:code", controller.UserResetPassword).
 		Name("users.reset-password")
 
+	r.Get("users/login/magic/:id/:code", controller.UserMagicLogin).
+		Name("users.login-magic")
+
 	return r
 }
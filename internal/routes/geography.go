@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/controller"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/middleware"
+)
+
+// rutas publicas de paises, estados y ciudades, los datos casi no cambian y se cachean en el cliente
+func geography(r *app.Routes) {
+
+	r.Prefix("geography", func() {
+		r.Get("countries", controller.CountryIndex).
+			Name("countries.index")
+
+		r.Get("countries/search", controller.CountrySearch).
+			Name("countries.search")
+
+		r.Get("countries/:id", controller.CountryShow).
+			Name("countries.show")
+
+		r.Get("countries/:id/states", controller.CountryStates).
+			Name("countries.states")
+
+		r.Get("countries/:id/cities", controller.CountryCities).
+			Name("countries.cities")
+
+		r.Get("states", controller.StateIndex).
+			Name("states.index")
+
+		r.Get("states/search", controller.StateSearch).
+			Name("states.search")
+
+		r.Get("states/:id", controller.StateShow).
+			Name("states.show")
+
+		r.Get("states/:id/cities", controller.StateCities).
+			Name("states.cities")
+
+		r.Get("cities", controller.CityIndex).
+			Name("cities.index")
+
+		r.Get("cities/search", controller.CitySearch).
+			Name("cities.search")
+
+		r.Get("cities/near", controller.CityNear).
+			Name("cities.near")
+
+		r.Get("cities/:id", controller.CityShow).
+			Name("cities.show")
+	}, middleware.Cache(24*time.Hour))
+
+	// administracion desde el dashboard, sin cache
+	r.Prefix("dashboard", func() {
+		r.Post("countries", controller.CountryStore).
+			Name("countries.store")
+
+		r.Patch("countries/:id", controller.CountryUpdate).
+			Name("countries.update")
+
+		r.Delete("countries/:id", controller.CountryDestroy).
+			Name("countries.destroy")
+
+		r.Patch("countries/:id/restore", controller.CountryRestore).
+			Name("countries.restore")
+
+		r.Post("states", controller.StateStore).
+			Name("states.store")
+
+		r.Patch("states/:id", controller.StateUpdate).
+			Name("states.update")
+
+		r.Delete("states/:id", controller.StateDestroy).
+			Name("states.destroy")
+
+		r.Patch("states/:id/restore", controller.StateRestore).
+			Name("states.restore")
+
+		r.Post("cities", controller.CityStore).
+			Name("cities.store")
+
+		r.Patch("cities/:id", controller.CityUpdate).
+			Name("cities.update")
+
+		r.Delete("cities/:id", controller.CityDestroy).
+			Name("cities.destroy")
+
+		r.Patch("cities/:id/restore", controller.CityRestore).
+			Name("cities.restore")
+	}, middleware.Auth)
+}
@@ -17,6 +17,48 @@ func user(r *app.Routes) {
 	r.Post("users/logout", controller.Logout, middleware.Auth).
 		Name("users.logout")
 
+	r.Post("users/login/two-factor", controller.UserLoginTwoFactor).
+		Name("users.login-two-factor")
+
+	r.Post("users/login/magic", controller.UserMagicLoginRequest).
+		Name("users.login-magic-request")
+
+	r.Post("users/login/magic/:id/:code", controller.UserMagicLogin).
+		Name("users.login-magic")
+
+	r.Post("users/login/code", controller.UserMagicLoginCode).
+		Name("users.login-code")
+
+	r.Post("users/token/refresh", controller.UserTokenRefresh).
+		Name("users.token-refresh")
+
+	r.Post("users/two-factor", controller.UserTwoFactorEnroll, middleware.Auth).
+		Name("users.two-factor.enroll")
+
+	r.Post("users/two-factor/confirm", controller.UserTwoFactorConfirm, middleware.Auth).
+		Name("users.two-factor.confirm")
+
+	r.Delete("users/two-factor", controller.UserTwoFactorDisable, middleware.Auth).
+		Name("users.two-factor.disable")
+
+	r.Get("users/sessions", controller.SessionIndex, middleware.Auth).
+		Name("users.sessions.index")
+
+	r.Delete("users/sessions", controller.SessionDestroyOthers, middleware.Auth).
+		Name("users.sessions.destroy-others")
+
+	r.Delete("users/sessions/:id", controller.SessionDestroy, middleware.Auth).
+		Name("users.sessions.destroy")
+
+	r.Get("users/api-tokens", controller.APITokenIndex, middleware.Auth).
+		Name("users.api-tokens.index")
+
+	r.Post("users/api-tokens", controller.APITokenStore, middleware.Auth).
+		Name("users.api-tokens.store")
+
+	r.Delete("users/api-tokens/:id", controller.APITokenDestroy, middleware.Auth).
+		Name("users.api-tokens.destroy")
+
 	r.Post("users/forgot-password", controller.UserForgotPassword).
 		Name("users.forgot-password")
 
@@ -36,6 +78,9 @@ func user(r *app.Routes) {
 		r.Get("users/export", controller.UserExport).
 			Name("users.export")
 
+		r.Get("users/stats", controller.UserStats).
+			Name("users.stats")
+
 		r.Get("users/trashed", controller.UserTrashed).
 			Name("users.trashed")
 
@@ -51,6 +96,9 @@ func user(r *app.Routes) {
 		r.Patch("users/:id/password", controller.UserUpdatePassword).
 			Name("users.update-password")
 
+		r.Patch("users/:id/preferences", controller.UserUpdatePreferences).
+			Name("users.update-preferences")
+
 		r.Delete("users/:id", controller.UserDestroy).
 			Name("users.destroy")
 
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"slices"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/resource"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/validator"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// los tokens de API son del usuario autenticado, cada uno solo ve y revoca los suyos
+
+func APITokenIndex(ctx *app.HttpContext) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	tokens := []*model.AccessToken{}
+	if err := model.NewAccessToken().Find(&tokens,
+		Filter(Where("user_id", Eq(current.UserID)), Where("type", Eq(model.ACCESS_TOKEN_TYPE_API))),
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	ctx.ResponseOk(resource.NewAPITokens(ctx, tokens))
+}
+
+func APITokenStore(ctx *app.HttpContext) {
+	// un token de API no puede crear otros, solo se crean desde una sesion
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.StoreAPIToken{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	user := model.NewUser()
+	if err := user.FindOneWith(Filter(Where("_id", Eq(current.UserID))), "roles.permissions", "permissions"); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	userPermissions := user.PermissionNames()
+	permissions := []string{}
+	for _, permission := range req.Permissions {
+		if !slices.Contains(userPermissions, permission) {
+			ctx.ResponseError(app.Errors.Forbiddenf("access denied: missing permission: :permission", app.E("permission", permission)))
+			return
+		}
+		if !slices.Contains(permissions, permission) {
+			permissions = append(permissions, permission)
+		}
+	}
+
+	token := model.NewAccessToken()
+	token.SetClient(ctx, req.Name)
+	if err := token.GenerateAPI(user.ID, req.Name, permissions, req.ExpiresAt); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(user.ID, token, "create-api-token", nil)
+
+	ctx.ResponseCreated(resource.NewAPIToken(ctx, token))
+}
+
+func APITokenDestroy(ctx *app.HttpContext) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	id, er := bson.ObjectIDFromHex(ctx.Params["id"])
+	if er != nil {
+		ctx.ResponseError(app.Errors.HexID(er))
+		return
+	}
+
+	token := model.NewAccessToken()
+	if err := token.FindOne(Filter(
+		Where("_id", Eq(id)),
+		Where("user_id", Eq(current.UserID)),
+		Where("type", Eq(model.ACCESS_TOKEN_TYPE_API)),
+	)); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if err := token.Revoke(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(current.UserID, token, "revoke-api-token", nil)
+
+	ctx.ResponseNoContent()
+}
@@ -0,0 +1,391 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/resource"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/policy"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// radio maximo en km para buscar ciudades cercanas
+const GEO_MAX_KM = 500
+
+// CityNear lista las ciudades cercanas a un punto ordenadas por distancia (en km)
+//
+//	?lat=4.711&lng=-74.072&km=50&filter[country_id]=...
+func CityNear(ctx *app.HttpContext) {
+	if err := policy.CityViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	query := ctx.Request.URL.Query()
+	lat, er := strconv.ParseFloat(query.Get("lat"), 64)
+	if er != nil || lat < -90 || lat > 90 {
+		ctx.ResponseError(app.Errors.BadRequestf("The lat must be a number between -90 and 90"))
+		return
+	}
+	lng, er := strconv.ParseFloat(query.Get("lng"), 64)
+	if er != nil || lng < -180 || lng > 180 {
+		ctx.ResponseError(app.Errors.BadRequestf("The lng must be a number between -180 and 180"))
+		return
+	}
+	km := 10.0
+	if query.Has("km") {
+		km, er = strconv.ParseFloat(query.Get("km"), 64)
+		if er != nil || km <= 0 || km > GEO_MAX_KM {
+			ctx.ResponseError(app.Errors.BadRequestf("The km must be a number between 0 and :max", app.E("max", GEO_MAX_KM)))
+			return
+		}
+	}
+
+	qf, err := ctx.QueryFilter("state_id", "country_id", "country_code", "state_code")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf.WithoutTrash()
+	city := model.NewCity()
+	match, err := qf.SetModel(city).BsonD()
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	pipeline := Pipeline(
+		GeoNear(app.NewGeoPoint(lat, lng), "distance",
+			MaxDistance(km*1000),
+			DistanceMultiplier(0.001),
+			NearQuery(match),
+		),
+	)
+	pipeline = append(pipeline, pageStages(qf)...)
+
+	cities := []resource.CityNear{}
+	if err := city.Aggregate(&cities, pipeline); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(cities)
+}
+
+// CitySearch busca ciudades por nombre, estado o pais ordenadas por relevancia
+//
+//	?q=bogota&filter[country_code]=CO
+func CitySearch(ctx *app.HttpContext) {
+	if err := policy.CityViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	cities := []resource.CitySearch{}
+	if err := searchByName(ctx, &city.Odm, &cities, "state_id", "country_id", "country_code", "state_code"); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(cities)
+}
+
+// CountrySearch busca paises por nombre ordenados por relevancia, ?q=colombia
+func CountrySearch(ctx *app.HttpContext) {
+	if err := policy.CountryViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country := model.NewCountry()
+	countries := []resource.CountrySearch{}
+	if err := searchByName(ctx, &country.Odm, &countries, "region.id", "subregion.id"); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	locales := ctx.Locales()
+	for i := range countries {
+		countries[i].Localize(locales...)
+	}
+	ctx.ResponseOk(countries)
+}
+
+// searchByName busca ?q= con el indice de texto en el idioma del Accept-Language, los empates por relevancia
+// se ordenan por nombre. El indice de texto ya ignora mayusculas y tildes y no acepta otra collation que la simple
+func searchByName(ctx *app.HttpContext, odm *app.Odm, result any, allowed ...string) app.Error {
+	search := strings.TrimSpace(ctx.Request.URL.Query().Get("q"))
+	if len([]rune(search)) < 2 {
+		return app.Errors.BadRequestf("The q must have at least :min characters", app.E("min", 2))
+	}
+
+	qf, err := ctx.QueryFilter(allowed...)
+	if err != nil {
+		return err
+	}
+	qf.WithoutTrash()
+	match, err := qf.SetModel(odm.Model).BsonD()
+	if err != nil {
+		return err
+	}
+	match = append(bson.D{Text(search, TextLanguage(ctx.Locale()))}, match...)
+
+	pipeline := Pipeline(
+		bson.D{{Key: "$match", Value: match}},
+		AddFields(TextScore("score")),
+		Sort(Element("score", -1), Element("name", 1)),
+	)
+	pipeline = append(pipeline, pageStages(qf)...)
+
+	return odm.Aggregate(result, pipeline)
+}
+
+// pageStages salta y limita segun page y per_page del QueryFilter
+func pageStages(qf *app.QueryFilter) []bson.D {
+	page := qf.Page
+	if page < 1 {
+		page = 1
+	}
+	return []bson.D{Skip((page - 1) * qf.PerPage), Limit(qf.PerPage)}
+}
+
+// CountryIndex lista los paises con los nombres en el idioma del Accept-Language
+func CountryIndex(ctx *app.HttpContext) {
+	if err := policy.CountryViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	qf, err := ctx.QueryFilter("id", "name", "iso2", "iso3", "region.id", "subregion.id")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if len(qf.Sort) == 0 {
+		qf.AppendSort("name", 1)
+	}
+	qf.WithoutTrash()
+
+	country := model.NewCountry()
+	countries := []*model.Country{}
+	paginator, err := country.Paginate(&countries, qf.SetModel(country))
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	locales := ctx.Locales()
+	for _, c := range countries {
+		c.Localize(locales...)
+	}
+	ctx.ResponsePaginated(paginator)
+}
+
+// CountryShow busca el pais por id o por el codigo iso2 o iso3 (ej: CO o COL)
+func CountryShow(ctx *app.HttpContext) {
+	if err := policy.CountryView(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country, err := findCountry(ctx.Params["id"])
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	country.Localize(ctx.Locales()...)
+	ctx.ResponseOk(country)
+}
+
+// StateIndex lista los estados, con filter[country_id] o filter[country_code] se limita a un pais
+func StateIndex(ctx *app.HttpContext) {
+	if err := policy.StateViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	qf, err := ctx.QueryFilter(stateFilters...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	paginateStates(ctx, qf)
+}
+
+// CountryStates lista los estados del pais
+func CountryStates(ctx *app.HttpContext) {
+	if err := policy.StateViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country, err := findCountry(ctx.Params["id"])
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf, err := ctx.QueryFilter(stateFilters...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf.Equals("country_id", country.ID.Hex())
+	paginateStates(ctx, qf)
+}
+
+func StateShow(ctx *app.HttpContext) {
+	if err := policy.StateView(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	if err := findActive(&state.Odm, ctx.Params["id"]); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(state)
+}
+
+// StateSearch busca estados por nombre o pais ordenados por relevancia, ?q=antioquia
+func StateSearch(ctx *app.HttpContext) {
+	if err := policy.StateViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	states := []resource.StateSearch{}
+	if err := searchByName(ctx, &state.Odm, &states, "country_id", "country_code", "type"); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(states)
+}
+
+// CityIndex lista las ciudades, se puede filtrar por estado o pais
+func CityIndex(ctx *app.HttpContext) {
+	if err := policy.CityViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	qf, err := ctx.QueryFilter(cityFilters...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	paginateCities(ctx, qf)
+}
+
+// CountryCities lista las ciudades del pais
+func CountryCities(ctx *app.HttpContext) {
+	if err := policy.CityViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country, err := findCountry(ctx.Params["id"])
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf, err := ctx.QueryFilter(cityFilters...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf.Equals("country_id", country.ID.Hex())
+	paginateCities(ctx, qf)
+}
+
+// StateCities lista las ciudades del estado, es la lista que usa el registro para Profile.CityID
+func StateCities(ctx *app.HttpContext) {
+	if err := policy.CityViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if _, er := bson.ObjectIDFromHex(ctx.Params["id"]); er != nil {
+		ctx.ResponseError(app.Errors.HexID(er))
+		return
+	}
+	qf, err := ctx.QueryFilter(cityFilters...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	qf.Equals("state_id", ctx.Params["id"])
+	paginateCities(ctx, qf)
+}
+
+func CityShow(ctx *app.HttpContext) {
+	if err := policy.CityView(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	if err := findActive(&city.Odm, ctx.Params["id"]); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(city)
+}
+
+// campos por los que se pueden filtrar y ordenar los estados y las ciudades
+var stateFilters = []string{"id", "name", "country_id", "country_code", "iso2", "type", "level", "parent_id"}
+var cityFilters = []string{"id", "name", "state_id", "state_code", "country_id", "country_code"}
+
+func paginateStates(ctx *app.HttpContext, qf *app.QueryFilter) {
+	if len(qf.Sort) == 0 {
+		qf.AppendSort("name", 1)
+	}
+	qf.WithoutTrash() // los eliminados se administran desde el dashboard
+	state := model.NewState()
+	states := []*model.State{}
+	paginator, err := state.Paginate(&states, qf.SetModel(state))
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponsePaginated(paginator)
+}
+
+func paginateCities(ctx *app.HttpContext, qf *app.QueryFilter) {
+	if len(qf.Sort) == 0 {
+		qf.AppendSort("name", 1)
+	}
+	qf.WithoutTrash()
+	city := model.NewCity()
+	cities := []*model.City{}
+	paginator, err := city.Paginate(&cities, qf.SetModel(city))
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponsePaginated(paginator)
+}
+
+// findCountry busca el pais por id o por el codigo iso2 o iso3, los eliminados no se muestran
+func findCountry(id string) (*model.Country, app.Error) {
+	country := model.NewCountry()
+	if objectID, er := bson.ObjectIDFromHex(id); er == nil {
+		return country, country.FindOne(Filter(Where("_id", Eq(objectID)), Where("deleted_at", Eq(nil))))
+	}
+	code := strings.ToUpper(id)
+	field := "iso2"
+	if len(code) == 3 {
+		field = "iso3"
+	}
+	return country, country.FindOne(Filter(Where(field, Eq(code)), Where("deleted_at", Eq(nil))))
+}
+
+// findActive busca por el id hexadecimal sin los eliminados con deleted_at
+func findActive(odm *app.Odm, id string) app.Error {
+	objectID, er := bson.ObjectIDFromHex(id)
+	if er != nil {
+		return app.Errors.HexID(er)
+	}
+	return odm.FindOne(Filter(Where("_id", Eq(objectID)), Where("deleted_at", Eq(nil))))
+}
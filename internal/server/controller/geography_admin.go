@@ -0,0 +1,403 @@
+package controller
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/policy"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/validator"
+)
+
+// administracion de paises, estados y ciudades desde el dashboard.
+// Se eliminan con deleted_at (no van a la papelera) por que los perfiles guardan el city_id.
+
+func CountryStore(ctx *app.HttpContext) {
+	if err := policy.CountryCreate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.StoreCountry{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country := model.NewCountry()
+	if _, _, err := app.Fill(country, req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	country.Location = *app.NewGeoPoint(req.Latitude, req.Longitude)
+	if err := country.Create(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), country, model.ACTION_CREATE, nil)
+
+	ctx.ResponseCreated(country)
+}
+
+func CountryUpdate(ctx *app.HttpContext) {
+	if err := policy.CountryUpdate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.UpdateCountry{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country := model.NewCountry()
+	if err := country.FindByHexID(ctx.Params["id"]); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	original, dirty, err := app.Fill(country, req)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	fillLocation(&country.Location, req.Latitude, req.Longitude, original, dirty)
+	if err := country.UpdateFields(dirty); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	// los estados y ciudades guardan una copia del nombre y el codigo del pais
+	if changed(dirty, "name", "iso2") {
+		if err := country.SyncChildren(); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), country, model.ACTION_UPDATE, original)
+
+	ctx.ResponseOk(country)
+}
+
+func CountryDestroy(ctx *app.HttpContext) {
+	if err := policy.CountryDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country := model.NewCountry()
+	if err := softDelete(ctx, &country.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+func CountryRestore(ctx *app.HttpContext) {
+	if err := policy.CountryDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	country := model.NewCountry()
+	if err := softRestore(ctx, &country.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+func StateStore(ctx *app.HttpContext) {
+	if err := policy.StateCreate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.StoreState{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	if _, _, err := app.Fill(state, req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	country := model.NewCountry()
+	if err := country.FindByID(state.CountryID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	state.SetCountry(country)
+	state.Location = *app.NewGeoPoint(req.Latitude, req.Longitude)
+	if state.Level == 0 {
+		state.Level = 1
+	}
+	if err := state.Create(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), state, model.ACTION_CREATE, nil)
+
+	ctx.ResponseCreated(state)
+}
+
+func StateUpdate(ctx *app.HttpContext) {
+	if err := policy.StateUpdate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.UpdateState{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	if err := state.FindByHexID(ctx.Params["id"]); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	original, dirty, err := app.Fill(state, req)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if changed(dirty, "country_id") {
+		country := model.NewCountry()
+		if err := country.FindByID(state.CountryID); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		state.SetCountry(country)
+		dirty["country_code"] = state.CountryCode
+		dirty["country_name"] = state.CountryName
+	}
+	fillLocation(&state.Location, req.Latitude, req.Longitude, original, dirty)
+	if err := state.UpdateFields(dirty); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if changed(dirty, "name", "iso2", "country_id") {
+		if err := state.SyncCities(); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), state, model.ACTION_UPDATE, original)
+
+	ctx.ResponseOk(state)
+}
+
+func StateDestroy(ctx *app.HttpContext) {
+	if err := policy.StateDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	if err := softDelete(ctx, &state.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+func StateRestore(ctx *app.HttpContext) {
+	if err := policy.StateDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	state := model.NewState()
+	if err := softRestore(ctx, &state.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+func CityStore(ctx *app.HttpContext) {
+	if err := policy.CityCreate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.StoreCity{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	if _, _, err := app.Fill(city, req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	state := model.NewState()
+	if err := state.FindByID(city.StateID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	city.SetState(state)
+	city.Location = *app.NewGeoPoint(req.Latitude, req.Longitude)
+	if err := city.Create(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), city, model.ACTION_CREATE, nil)
+
+	ctx.ResponseCreated(city)
+}
+
+func CityUpdate(ctx *app.HttpContext) {
+	if err := policy.CityUpdate(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	req := &validator.UpdateCity{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	if err := city.FindByHexID(ctx.Params["id"]); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	original, dirty, err := app.Fill(city, req)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if changed(dirty, "state_id") {
+		state := model.NewState()
+		if err := state.FindByID(city.StateID); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		city.SetState(state)
+		dirty["state_code"] = city.StateCode
+		dirty["state_name"] = city.StateName
+		dirty["country_id"] = city.CountryID
+		dirty["country_code"] = city.CountryCode
+		dirty["country_name"] = city.CountryName
+	}
+	fillLocation(&city.Location, req.Latitude, req.Longitude, original, dirty)
+	if err := city.UpdateFields(dirty); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), city, model.ACTION_UPDATE, original)
+
+	ctx.ResponseOk(city)
+}
+
+func CityDestroy(ctx *app.HttpContext) {
+	if err := policy.CityDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	if err := softDelete(ctx, &city.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+func CityRestore(ctx *app.HttpContext) {
+	if err := policy.CityDelete(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	city := model.NewCity()
+	if err := softRestore(ctx, &city.Odm); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseNoContent()
+}
+
+// softDelete marca el documento del parametro :id con deleted_at
+func softDelete(ctx *app.HttpContext, odm *app.Odm) app.Error {
+	if err := odm.FindByHexID(ctx.Params["id"]); err != nil {
+		return err
+	}
+	if err := odm.UpdateOne(
+		Filter(Where("_id", Eq(odm.Model.GetID()))),
+		Set(Element("deleted_at", time.Now())),
+	); err != nil {
+		return err
+	}
+	go model.HistoryRecord(ctx.Auth.GetUserID(), odm.Model, model.ACTION_DELETE, nil)
+	return nil
+}
+
+// softRestore quita el deleted_at del documento del parametro :id
+func softRestore(ctx *app.HttpContext, odm *app.Odm) app.Error {
+	if err := odm.FindByHexID(ctx.Params["id"]); err != nil {
+		return err
+	}
+	if err := odm.UpdateOne(
+		Filter(Where("_id", Eq(odm.Model.GetID()))),
+		Unset("deleted_at"),
+	); err != nil {
+		return err
+	}
+	go model.HistoryRecord(ctx.Auth.GetUserID(), odm.Model, model.ACTION_RESTORE, nil)
+	return nil
+}
+
+// fillLocation arma el GeoPoint con latitude y longitude y lo agrega a dirty si cambio.
+// Si solo viene una de las dos se conserva la otra del punto actual
+func fillLocation(location *app.GeoPoint, latitude *float64, longitude *float64, original map[string]any, dirty map[string]any) {
+	if latitude == nil && longitude == nil {
+		return
+	}
+	lat, lng := 0.0, 0.0
+	if len(location.Coordinates) == 2 {
+		lng, lat = location.Coordinates[0], location.Coordinates[1]
+	}
+	if latitude != nil {
+		lat = *latitude
+	}
+	if longitude != nil {
+		lng = *longitude
+	}
+	point := *app.NewGeoPoint(lat, lng)
+	if reflect.DeepEqual(*location, point) {
+		return
+	}
+	original["location"] = *location
+	dirty["location"] = point
+	*location = point
+}
+
+// changed indica si alguno de los campos esta en dirty
+func changed(dirty map[string]any, keys ...string) bool {
+	for _, key := range keys {
+		if _, ok := dirty[key]; ok {
+			return true
+		}
+	}
+	return false
+}
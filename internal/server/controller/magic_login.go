@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/service"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/validator"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// login sin contraseña, el correo trae un enlace y un codigo de 6 digitos y la sesion sale por completeLogin como en runLogin
+
+// UserMagicLoginRequest envia el correo, responde lo mismo exista o no el correo para no revelar usuarios
+func UserMagicLoginRequest(ctx *app.HttpContext) {
+	req := &validator.MagicLogin{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	message := map[string]string{"message": "If the email is registered you will receive a link and a code to sign in."}
+
+	user := model.NewUser()
+	if err := user.FindOne(Filter(Where("email", Eq(req.Email)))); err != nil || user.DeletedAt != nil {
+		if err != nil && err.GetStatus() != http.StatusNotFound {
+			ctx.ResponseError(err)
+			return
+		}
+		ctx.ResponseOk(message)
+		return
+	}
+
+	// un correo por usuario cada MAGIC_LOGIN_COOLDOWN, responde lo mismo para no revelar que existe
+	if err := model.ReserveAttempt(user.ID, model.MAGIC_LOGIN_REQUEST, 1, model.MAGIC_LOGIN_COOLDOWN*time.Second); err != nil {
+		if err.GetStatus() != http.StatusTooManyRequests {
+			ctx.ResponseError(err)
+			return
+		}
+		ctx.ResponseOk(message)
+		return
+	}
+
+	// solo sirve el ultimo correo, los anteriores se eliminan (puede que no haya ninguno, no se usa Odm.DeleteMany)
+	if _, er := app.DB.Collection(model.NewVerificationCode().CollectionName()).DeleteMany(context.TODO(), Filter(
+		Where("user_id", Eq(user.ID)),
+		Where("type", Eq(model.MAGIC_LOGIN)),
+	)); er != nil {
+		ctx.ResponseError(app.Errors.Mongo(er))
+		return
+	}
+
+	go model.HistoryRecord(user.ID, user, "magic-login-request", nil)
+	go service.SendEmailMagicLogin(user, req.DeviceName)
+
+	ctx.ResponseOk(message)
+}
+
+// UserMagicLogin entra con el enlace del correo
+func UserMagicLogin(ctx *app.HttpContext) {
+	id, er := bson.ObjectIDFromHex(ctx.Params["id"])
+	if er != nil {
+		ctx.ResponseError(app.Errors.HexID(er))
+		return
+	}
+
+	magicCode := model.NewVerificationCode()
+	if err := magicCode.FindByCode(id, model.MAGIC_LOGIN, ctx.Params["code"]); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			err = app.Errors.Unauthorizedf("The sign in link is invalid or has expired.")
+		}
+		ctx.ResponseError(err)
+		return
+	}
+
+	magicLogin(ctx, magicCode)
+}
+
+// UserMagicLoginCode entra con el correo y el codigo de 6 digitos
+func UserMagicLoginCode(ctx *app.HttpContext) {
+	req := &validator.MagicLoginCode{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	invalid := app.Errors.Unauthorizedf("The sign in code is invalid or has expired.")
+
+	user := model.NewUser()
+	if err := user.FindOne(Filter(Where("email", Eq(req.Email)))); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			err = invalid
+		}
+		ctx.ResponseError(err)
+		return
+	}
+
+	magicCode := model.NewVerificationCode()
+	if err := magicCode.FindOne(Filter(
+		Where("user_id", Eq(user.ID)),
+		Where("type", Eq(model.MAGIC_LOGIN)),
+		Where("used_at", Eq(nil)),
+	)); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			err = invalid
+		}
+		ctx.ResponseError(err)
+		return
+	}
+
+	// 6 digitos se pueden adivinar, el intento se cuenta por usuario antes de comparar asi pedir otro correo
+	// o enviar varios en paralelo no da mas intentos
+	if err := model.ReserveAttempt(user.ID, model.MAGIC_LOGIN, model.MAGIC_LOGIN_MAX_ATTEMPTS, model.MAGIC_LOGIN_LIFETIME*time.Minute); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if !magicCode.VerifyOTP(req.Code) {
+		ctx.ResponseError(invalid)
+		return
+	}
+	if err := model.ResetAttempts(user.ID, model.MAGIC_LOGIN); err != nil {
+		app.PrintError("Fail to reset magic login attempts :error", app.E("error", err.Error()))
+	}
+
+	magicLogin(ctx, magicCode)
+}
+
+// magicLogin consume el codigo una sola vez y entrega la sesion igual que el login con contraseña
+func magicLogin(ctx *app.HttpContext, magicCode *model.VerificationCode) {
+	if !magicCode.Usable() {
+		ctx.ResponseError(app.Errors.Unauthorizedf("The sign in code is invalid or has expired."))
+		return
+	}
+
+	user := model.NewUser()
+	if err := user.FindOneWith(Filter(Where("_id", Eq(magicCode.UserID))), "roles.permissions", "permissions"); err != nil || user.DeletedAt != nil {
+		ctx.ResponseError(app.Errors.Unauthorizedf("User Inactive or Deleted."))
+		return
+	}
+
+	if err := magicCode.Consume(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(user.ID, user, "magic-login", nil)
+
+	completeLogin(ctx, user, magicCode.Metadata["device_name"])
+}
@@ -203,7 +203,7 @@ func PermissionGrant(ctx *app.HttpContext) {
 	}
 
 	user.PermissionIDs = append(user.PermissionIDs, permission.ID)
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"permission_ids": user.PermissionIDs}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -255,7 +255,7 @@ func PermissionRevoke(ctx *app.HttpContext) {
 		}
 	}
 
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"permission_ids": user.PermissionIDs}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
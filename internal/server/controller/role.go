@@ -15,14 +15,40 @@ func RoleIndex(ctx *app.HttpContext) {
 		return
 	}
 
+	fields, err := ctx.Fields("id", "name")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	include, err := ctx.Include("permissions")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
 	role := model.NewRole()
+	lookups, err := role.With(include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	pipeline := Pipeline(Match())
+	pipeline = append(pipeline, lookups...)
+	if stage := app.ProjectFields(role, fields, include...); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
 	roles := []model.Role{}
-	role.Aggregate(&roles, Pipeline(
-		Match(),
-		role.WithPermissions(),
-	))
+	if err := role.Aggregate(&roles, pipeline); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
 
-	ctx.ResponseOk(roles)
+	data, err := app.SparseFields(roles, fields, include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(data)
 }
 
 func RoleExport(ctx *app.HttpContext) {
@@ -33,10 +59,10 @@ func RoleExport(ctx *app.HttpContext) {
 
 	role := model.NewRole()
 	roles := []model.Role{}
-	role.Aggregate(&roles, Pipeline(
-		Match(),
-		role.WithPermissions(),
-	))
+	if err := role.FindWith(&roles, Filter(), "permissions"); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
 
 	ctx.ResponseCSV("roles", roles)
 }
@@ -69,16 +95,39 @@ func RoleShow(ctx *app.HttpContext) {
 		return
 	}
 
+	fields, err := ctx.Fields("id", "name")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	include, err := ctx.Include("permissions")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
 	role := model.NewRole()
-	if err := role.AggregateOne(Pipeline(
-		Match(Where("_id", Eq(id))),
-		role.WithPermissions(),
-	)); err != nil {
+	lookups, err := role.With(include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	pipeline := Pipeline(Match(Where("_id", Eq(id))))
+	pipeline = append(pipeline, lookups...)
+	if stage := app.ProjectFields(role, fields, include...); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
+	if err := role.AggregateOne(pipeline); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	ctx.ResponseOk(role)
+	data, err := app.SparseFields(role, fields, include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(data)
 }
 
 func RoleStore(ctx *app.HttpContext) {
@@ -211,7 +260,7 @@ func RoleGrant(ctx *app.HttpContext) {
 	}
 
 	user.RoleIDs = append(user.RoleIDs, role.ID)
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"role_ids": user.RoleIDs}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -263,7 +312,7 @@ func RoleRevoke(ctx *app.HttpContext) {
 		}
 	}
 
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"role_ids": user.RoleIDs}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/resource"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// las sesiones son las familias de refresh tokens vivas del usuario autenticado, cada uno solo ve y cierra las suyas.
+// El access token vence mucho antes que la sesion, por eso no se listan los access tokens
+
+func SessionIndex(ctx *app.HttpContext) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	// el ultimo refresh token de cada familia es el que no se ha usado
+	heads := []*model.RefreshToken{}
+	if err := model.NewRefreshToken().Find(&heads,
+		Filter(
+			Where("user_id", Eq(current.UserID)),
+			Where("used_at", Eq(nil)),
+			Where("revoked_at", Eq(nil)),
+			Where("expires_at", Gt(time.Now())),
+		),
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	// el access token vigente de la sesion tiene la ultima actividad, puede que ya haya vencido
+	ids := []any{}
+	for _, head := range heads {
+		ids = append(ids, head.AccessTokenID)
+	}
+	tokens := []*model.AccessToken{}
+	if len(ids) > 0 {
+		if err := model.NewAccessToken().Find(&tokens, Filter(Where("_id", In(ids...)))); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+	}
+
+	ctx.ResponseOk(resource.NewSessions(ctx, heads, tokens, current.ID))
+}
+
+// SessionDestroy cierra la sesion del parametro :id, es el family_id de la lista
+func SessionDestroy(ctx *app.HttpContext) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	id, er := bson.ObjectIDFromHex(ctx.Params["id"])
+	if er != nil {
+		ctx.ResponseError(app.Errors.HexID(er))
+		return
+	}
+
+	// el filtro por user_id evita cerrar sesiones de otro usuario, responde 404 como si no existiera
+	session := model.NewRefreshToken()
+	if err := session.FindOne(Filter(
+		Where("family_id", Eq(id)),
+		Where("user_id", Eq(current.UserID)),
+		Where("used_at", Eq(nil)),
+		Where("revoked_at", Eq(nil)),
+	)); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if err := model.RevokeRefreshFamily(session.FamilyID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(current.UserID, session, "revoke-session", nil)
+
+	ctx.ResponseNoContent()
+}
+
+// SessionDestroyOthers cierra todas las sesiones menos con la que se hizo la peticion
+func SessionDestroyOthers(ctx *app.HttpContext) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if err := model.RevokeUserSessions(current.UserID, current.ID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(current.UserID, current, "revoke-other-sessions", nil)
+
+	ctx.ResponseNoContent()
+}
+
+// currentAccessToken el token de la peticion para las rutas de la propia cuenta (sesiones, tokens de API, 2FA),
+// un token de API no las puede usar aunque tenga permisos, solo una sesion
+func currentAccessToken(ctx *app.HttpContext) (*model.AccessToken, app.Error) {
+	accessToken, ok := ctx.Auth.(*model.AccessToken)
+	if !ok {
+		return nil, app.Errors.InternalServerErrorf("Invalid auth token type.")
+	}
+	if accessToken.IsAPI() {
+		return nil, app.Errors.Forbiddenf("API tokens can not manage the account, sign in to do it.")
+	}
+	return accessToken, nil
+}
@@ -0,0 +1,177 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"github.com/donbarrigon/nuevo-proyecto/internal/resource"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/validator"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// el 2FA lo administra cada usuario sobre su cuenta y solo desde una sesion, no con tokens de API
+
+// UserTwoFactorEnroll inicia la activacion, retorna el secreto para la app de autenticacion
+func UserTwoFactorEnroll(ctx *app.HttpContext) {
+	user, err := twoFactorUser(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	secret, err := user.EnrollTwoFactor()
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	ctx.ResponseOk(&resource.UserTwoFactorEnroll{
+		Secret: secret,
+		URI:    app.TOTPURI(secret, user.Email),
+	})
+}
+
+// UserTwoFactorConfirm activa el 2FA con el primer codigo y retorna los codigos de recuperacion
+func UserTwoFactorConfirm(ctx *app.HttpContext) {
+	req := &validator.TwoFactorCode{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	user, err := twoFactorUser(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	codes, err := user.ConfirmTwoFactor(req.Code)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(user.ID, user, "enable-two-factor", nil)
+
+	ctx.ResponseOk(&resource.UserRecoveryCodes{RecoveryCodes: codes})
+}
+
+// UserTwoFactorDisable pide la contraseña y un codigo para que una sesion robada no lo pueda quitar
+func UserTwoFactorDisable(ctx *app.HttpContext) {
+	req := &validator.DisableTwoFactor{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	user, err := twoFactorUser(ctx)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		ctx.ResponseError(app.Errors.Unauthorizedf("Invalid login credentials."))
+		return
+	}
+	// el mismo contador del login, una sesion robada con la contraseña no puede probar codigos sin limite
+	if err := model.ReserveAttempt(user.ID, model.TWO_FACTOR_LOGIN, model.TWO_FACTOR_MAX_ATTEMPTS, model.TWO_FACTOR_ATTEMPTS_WINDOW*time.Minute); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if err := user.VerifyTwoFactor(req.Code); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if err := model.ResetAttempts(user.ID, model.TWO_FACTOR_LOGIN); err != nil {
+		app.PrintError("Fail to reset two-factor attempts :error", app.E("error", err.Error()))
+	}
+
+	if err := user.DisableTwoFactor(); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(user.ID, user, "disable-two-factor", nil)
+
+	ctx.ResponseNoContent()
+}
+
+// UserLoginTwoFactor segundo paso del login, cambia el token pendiente y el codigo por la sesion
+func UserLoginTwoFactor(ctx *app.HttpContext) {
+	req := &validator.LoginTwoFactor{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	invalid := app.Errors.Unauthorizedf("The two-factor token is invalid or has expired.")
+	hexID, code, ok := strings.Cut(req.TwoFactorToken, ".")
+	if !ok {
+		ctx.ResponseError(invalid)
+		return
+	}
+	userID, er := bson.ObjectIDFromHex(hexID)
+	if er != nil {
+		ctx.ResponseError(invalid)
+		return
+	}
+
+	pending := model.NewVerificationCode()
+	if err := pending.FindByCode(userID, model.TWO_FACTOR_LOGIN, code); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			err = invalid
+		}
+		ctx.ResponseError(err)
+		return
+	}
+	if !pending.Usable() {
+		ctx.ResponseError(invalid)
+		return
+	}
+
+	user := model.NewUser()
+	if err := user.FindOneWith(Filter(Where("_id", Eq(userID))), "roles.permissions", "permissions"); err != nil || user.DeletedAt != nil {
+		ctx.ResponseError(invalid)
+		return
+	}
+
+	// con pocos intentos por usuario no se alcanza a adivinar un codigo de 6 digitos, el intento se cuenta
+	// antes de comparar y repetir la contraseña para tener otro token pendiente no reinicia la cuenta
+	if err := model.ReserveAttempt(user.ID, model.TWO_FACTOR_LOGIN, model.TWO_FACTOR_MAX_ATTEMPTS, model.TWO_FACTOR_ATTEMPTS_WINDOW*time.Minute); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if err := user.VerifyTwoFactor(req.Code); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if err := model.ResetAttempts(user.ID, model.TWO_FACTOR_LOGIN); err != nil {
+		app.PrintError("Fail to reset two-factor attempts :error", app.E("error", err.Error()))
+	}
+
+	// el token pendiente sirve una sola vez, con dos peticiones en paralelo solo una lo consume y recibe la sesion
+	if err := pending.Consume(); err != nil {
+		ctx.ResponseError(invalid)
+		return
+	}
+
+	issueLogin(ctx, user, pending.Metadata["device_name"])
+}
+
+func twoFactorUser(ctx *app.HttpContext) (*model.User, app.Error) {
+	current, err := currentAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user := model.NewUser()
+	if err := user.FindByID(current.UserID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"math/big"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
@@ -14,25 +15,85 @@ import (
 	"github.com/donbarrigon/nuevo-proyecto/internal/server/service"
 	"github.com/donbarrigon/nuevo-proyecto/internal/server/validator"
 	"go.mongodb.org/mongo-driver/v2/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// campos que se pueden pedir con ?fields= en los endpoints de usuarios
+var userFields = []string{
+	"id", "email", "email_verified_at", "created_at", "updated_at", "deleted_at",
+	"profile", "profile.avatar", "profile.full_name", "profile.nickname",
+	"profile.phone_number", "profile.discord_username", "profile.city_id",
+}
+
 func UserIndex(ctx *app.HttpContext) {
 	if err := policy.UserViewAny(ctx); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
+	qf, err := ctx.QueryFilter("id", "email", "profile.nickname", "profile.full_name", "profile.city_id", "email_verified_at", "created_at", "updated_at")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
 	user := model.NewUser()
+	if err := qf.Select(ctx, userFields, []string{"roles", "roles.permissions", "permissions"}); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if qf.Lookups, err = user.With(qf.Include...); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
 	users := []*model.User{}
-	if err := user.Find(&users, Filter(WithOutTrashed())); err != nil {
+	if qf.Page == 0 {
+		paginator, err := user.CursorPaginate(&users, qf.SetModel(user))
+		if err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		ctx.ResponseCursorPaginated(paginator)
+		return
+	}
+
+	paginator, err := user.Paginate(&users, qf.SetModel(user))
+	if err != nil {
 		ctx.ResponseError(err)
 		return
 	}
-	app.PrintInfo("estas mirando todos los usuarios de la base de datos por que tienes permiso de hacerlo")
 
-	ctx.ResponseOk(users)
+	ctx.ResponsePaginated(paginator)
+}
+
+// UserStats datos para las graficas del dashboard (ej: ?group_by=profile.city_id o ?group_by=created_at:month)
+func UserStats(ctx *app.HttpContext) {
+	if err := policy.UserViewAny(ctx); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	allowed := []string{"profile.city_id", "email_verified_at", "created_at", "updated_at"}
+	qf, err := ctx.QueryFilter(append(allowed, app.QUERY_GROUP_BY)...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	user := model.NewUser()
+	pipeline, err := Analytics(ctx, qf.SetModel(user), allowed...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	stats := []bson.M{}
+	if err := user.Aggregate(&stats, pipeline); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	ctx.ResponseOk(stats)
 }
 
 func UserExport(ctx *app.HttpContext) {
@@ -42,13 +103,29 @@ func UserExport(ctx *app.HttpContext) {
 	}
 
 	user := model.NewUser()
-	users := []*model.User{}
-	if err := user.Find(&users, Document(WithOutTrashed())); err != nil {
+	cursor, err := user.FindCursor(Document(WithOutTrashed()))
+	if err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	ctx.ResponseCSV("users", users)
+	// se escribe fila por fila para no cargar todos los usuarios en memoria
+	if ctx.GetInput("format") == "ndjson" {
+		stream := ctx.StreamNDJSON("users")
+		if err := app.Each(cursor, func(u *model.User) app.Error { return stream.Write(u) }); err != nil {
+			app.PrintError("Fail to export users: :error", app.E("error", err.Error()))
+			stream.Fail(err)
+		}
+		stream.Close()
+		return
+	}
+
+	stream := ctx.StreamCSV("users")
+	if err := app.Each(cursor, func(u *model.User) app.Error { return stream.Write(u) }); err != nil {
+		app.PrintError("Fail to export users: :error", app.E("error", err.Error()))
+		stream.Fail(err)
+	}
+	stream.Close()
 }
 
 func UserTrashed(ctx *app.HttpContext) {
@@ -74,24 +151,50 @@ func UserShow(ctx *app.HttpContext) {
 		return
 	}
 
+	fields, err := ctx.Fields(userFields...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	include, err := ctx.Include("roles", "roles.permissions", "permissions", "access_tokens")
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
 	user := model.NewUser()
-	err := user.AggregateOne(mongo.Pipeline{
-		Match(Where("_id", Eq(id))),
-		user.WithRoles(),
-		user.WhithPermissions(),
-		user.WithTokens(),
-	})
+	lookups, err := user.With(include...)
 	if err != nil {
 		ctx.ResponseError(err)
 		return
 	}
+	pipeline := Pipeline(Match(Where("_id", Eq(id))))
+	pipeline = append(pipeline, lookups...)
+	if stage := app.ProjectFields(user, fields, include...); stage != nil {
+		pipeline = append(pipeline, stage)
+	}
+	if err := user.AggregateOne(pipeline); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
 
 	if err := policy.UserView(ctx, user); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
+	if slices.Contains(include, "access_tokens") {
+		if err := policy.UserViewSessions(ctx, user); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+	}
 
-	ctx.ResponseOk(user)
+	data, err := app.SparseFields(user, fields, include...)
+	if err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	ctx.ResponseOk(data)
 }
 
 func UserStore(ctx *app.HttpContext) {
@@ -110,6 +213,13 @@ func UserStore(ctx *app.HttpContext) {
 	user := model.NewUser()
 	user.Email = req.Email
 	app.Fill(user.Profile, req)
+	user.Profile.Preferences = model.DefaultPreferences(user.Profile.CityID)
+	if req.Timezone != "" {
+		user.Profile.Preferences.Timezone = req.Timezone
+	}
+	if req.Locale != "" {
+		user.Profile.Preferences.Locale = req.Locale
+	}
 	hashedPassword, er := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if er != nil {
 		ctx.ResponseError(&app.Err{
@@ -136,7 +246,7 @@ func UserStore(ctx *app.HttpContext) {
 	go model.HistoryRecord(user.ID, user, "create", nil)
 	go service.SendEmailConfirm(user)
 
-	runLogin(ctx, req.Email, req.Password)
+	runLogin(ctx, req.Email, req.Password, "")
 }
 
 func Login(ctx *app.HttpContext) {
@@ -147,16 +257,12 @@ func Login(ctx *app.HttpContext) {
 		return
 	}
 
-	runLogin(ctx, req.Email, req.Password)
+	runLogin(ctx, req.Email, req.Password, req.DeviceName)
 }
-func runLogin(ctx *app.HttpContext, email string, password string) {
+func runLogin(ctx *app.HttpContext, email string, password string, deviceName string) {
 
 	user := model.NewUser()
-	err := user.AggregateOne(mongo.Pipeline{
-		Match(Where("email", Eq(email))),
-		user.WithRoles(),
-		user.WhithPermissions(),
-	})
+	err := user.FindOneWith(Filter(Where("email", Eq(email))), "roles.permissions", "permissions")
 	if err != nil {
 		ctx.ResponseError(&app.Err{
 			Status:  http.StatusUnauthorized,
@@ -175,44 +281,96 @@ func runLogin(ctx *app.HttpContext, email string, password string) {
 		return
 	}
 
-	permissions := []string{}
-	exists := false
-	for _, role := range user.Roles {
-		for _, permission := range role.Permissions {
+	completeLogin(ctx, user, deviceName)
+}
 
-			for _, p := range permissions {
-				if p == permission.Name {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				permissions = append(permissions, permission.Name)
-			}
+// completeLogin el usuario ya probo el primer factor (contraseña o correo), si tiene 2FA
+// la sesion se entrega despues de validar el codigo en UserLoginTwoFactor
+func completeLogin(ctx *app.HttpContext, user *model.User, deviceName string) {
+	if user.TwoFactorEnabled() {
+		pending := model.NewVerificationCode()
+		pending.ExpiresAt = time.Now().Add(model.TWO_FACTOR_LOGIN_LIFETIME * time.Minute)
+		if err := pending.GenerateHashed(user.ID, model.TWO_FACTOR_LOGIN, map[string]string{"device_name": deviceName}); err != nil {
+			ctx.ResponseError(err)
+			return
 		}
+		ctx.ResponseOk(resource.NewUserTwoFactorPending(pending))
+		return
 	}
 
-	for _, permission := range user.Permissions {
-		for _, p := range permissions {
-			if p == permission.Name {
-				exists = true
-				break
-			}
-		}
-		if !exists {
-			permissions = append(permissions, permission.Name)
+	issueLogin(ctx, user, deviceName)
+}
+
+// issueLogin crea la sesion (access token y refresh token) del usuario ya autenticado
+func issueLogin(ctx *app.HttpContext, user *model.User, deviceName string) {
+	accessToken := model.NewAccessToken()
+	accessToken.SetClient(ctx, deviceName)
+	if err := accessToken.Generate(user.ID, user.PermissionNames()); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	refreshToken := model.NewRefreshToken()
+	if err := refreshToken.Generate(accessToken, bson.ObjectID{}); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(user.ID, accessToken, "login", nil)
+
+	ctx.ResponseOk(resource.NewUserLogin(user, accessToken, refreshToken))
+
+}
+
+// UserTokenRefresh cambia el refresh token por un access token nuevo y un refresh token nuevo de la misma familia
+func UserTokenRefresh(ctx *app.HttpContext) {
+
+	req := &validator.RefreshToken{}
+	if err := ctx.ValidateBody(req); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	current := model.NewRefreshToken()
+	if err := current.Rotate(req.RefreshToken); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	user := model.NewUser()
+	if err := user.FindOneWith(Filter(Where("_id", Eq(current.UserID))), "roles.permissions", "permissions"); err != nil || user.DeletedAt != nil {
+		if err := model.RevokeRefreshFamily(current.FamilyID); err != nil {
+			app.PrintError("Fail to revoke refresh token family :error", app.E("error", err.Error()))
 		}
+		ctx.ResponseError(app.Errors.Unauthorizedf("User Inactive or Deleted."))
+		return
 	}
+
 	accessToken := model.NewAccessToken()
-	if err := accessToken.Generate(user.ID, permissions); err != nil {
+	accessToken.SetClient(ctx, current.DeviceName)
+	if err := accessToken.Generate(user.ID, user.PermissionNames()); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	go model.HistoryRecord(user.ID, accessToken, "login", nil)
+	refreshToken := model.NewRefreshToken()
+	if err := refreshToken.Generate(accessToken, current.FamilyID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	// el access token anterior se reemplaza aunque ya haya vencido, Revoke solo agrega a la lista los JWT vigentes
+	previous := model.NewAccessToken()
+	previous.ID = current.AccessTokenID
+	previous.UserID = current.UserID
+	previous.ExpiresAt = current.AccessExpiresAt
+	if err := previous.Revoke(); err != nil {
+		app.PrintWarning("Fail to revoke previous access token :error", app.E("error", err.Error()))
+	}
 
-	ctx.ResponseOk(resource.NewUserLogin(user, accessToken))
+	go model.HistoryRecord(user.ID, accessToken, "refresh", nil)
 
+	ctx.ResponseOk(resource.NewUserLogin(user, accessToken, refreshToken))
 }
 
 func UserUpdateEmail(ctx *app.HttpContext) {
@@ -251,23 +409,15 @@ func UserUpdateEmail(ctx *app.HttpContext) {
 
 	oldEmail := user.Email
 	user.Email = req.Email
-	if err := user.Update(); err != nil {
+	user.EmailVerifiedAt = nil
+	if err := user.UpdateFields(map[string]any{
+		"email":             user.Email,
+		"email_verified_at": nil,
+	}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	if err := user.UpdateOne(
-		Filter(Where("_id", Eq(user.ID))),
-		Unset("email_verified_at"),
-	); err != nil {
-		if err.GetMessage() != "No records updated" {
-			ctx.ResponseError(err)
-			return
-		}
-	}
-	// filter := bson.D{bson.E{Key: "_id", Value: o.Model.GetID()}}
-	// update := bson.D{bson.E{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: nil}}}}
-
 	go model.HistoryRecord(ctx.Auth.GetUserID(), user, "update-email", map[string]string{"email": oldEmail})
 	go service.SendEmailConfirm(user)
 	go service.SendEmailChanged(user, oldEmail)
@@ -300,20 +450,74 @@ func UserUpdateProfile(ctx *app.HttpContext) {
 		return
 	}
 
-	original, _, err := app.Fill(user.Profile, req)
+	original, _, err := user.UpdateBy(req)
 	if err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	if err := user.Update(); err != nil {
+	go model.HistoryRecord(ctx.Auth.GetUserID(), user, "update-profile", original)
+
+	ctx.ResponseOk(user)
+}
+
+// UserUpdatePreferences cambia la zona horaria, idioma, formato de fecha y notificaciones,
+// si el usuario no tenia preferencias se parte de las de su ciudad
+func UserUpdatePreferences(ctx *app.HttpContext) {
+
+	req := &validator.UpdateUserPreferences{}
+	if err := ctx.ValidateBody(req); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	go model.HistoryRecord(ctx.Auth.GetUserID(), user, "update-profile", original)
+	id, er := bson.ObjectIDFromHex(ctx.Params["id"])
+	if er != nil {
+		ctx.ResponseError(app.Errors.HexID(er))
+		return
+	}
 
-	ctx.ResponseOk(user)
+	user := model.NewUser()
+	if err := user.FindOne(Filter(Where("_id", Eq(id)))); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	if err := policy.UserUpdate(ctx, user); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	old := user.Profile.Preferences
+	preferences := *user.Profile.GetPreferences()
+	if req.Timezone != "" {
+		preferences.Timezone = req.Timezone
+	}
+	if req.Locale != "" {
+		preferences.Locale = req.Locale
+	}
+	if req.DateFormat != "" {
+		preferences.DateFormat = req.DateFormat
+	}
+	if req.NotifyByEmail != nil {
+		preferences.Notifications.Email = *req.NotifyByEmail
+	}
+	if req.NotifyByPush != nil {
+		preferences.Notifications.Push = *req.NotifyByPush
+	}
+	if req.NotifyByMarketing != nil {
+		preferences.Notifications.Marketing = *req.NotifyByMarketing
+	}
+
+	user.Profile.Preferences = &preferences
+	if err := user.UpdateFields(map[string]any{"profile.preferences": user.Profile.Preferences}); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+
+	go model.HistoryRecord(ctx.Auth.GetUserID(), user, "update-preferences", map[string]any{"profile.preferences": old})
+
+	ctx.ResponseOk(user.Profile.Preferences)
 }
 
 func UserUpdatePassword(ctx *app.HttpContext) {
@@ -361,15 +565,27 @@ func UserUpdatePassword(ctx *app.HttpContext) {
 	}
 	user.Password = string(hashedPassword)
 
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"password": user.Password}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
 
-	accesToken := model.NewAccessToken()
-	if err := accesToken.DeleteMany(Filter(Where("user_id", Eq(user.ID)))); err != nil {
-		ctx.ResponseError(err)
-		return
+	// si el usuario cambia su propia contraseña conserva la sesion actual, si la cambia un admin o un token de API
+	// se cierran todas las sesiones y los tokens de API
+	if current, err := currentAccessToken(ctx); err == nil && current.UserID == user.ID {
+		if err := model.RevokeUserSessions(user.ID, ctx.Auth.GetID()); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+	} else {
+		if err := model.RevokeUserSessions(user.ID); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
+		if err := model.RevokeUserAPITokens(user.ID); err != nil {
+			ctx.ResponseError(err)
+			return
+		}
 	}
 
 	go model.HistoryRecord(ctx.Auth.GetUserID(), user, "update-password", nil)
@@ -404,7 +620,7 @@ func UserConfirmEmail(ctx *app.HttpContext) {
 
 	now := time.Now()
 	user.EmailVerifiedAt = &now
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"email_verified_at": user.EmailVerifiedAt}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -456,7 +672,10 @@ func UserRevertEmail(ctx *app.HttpContext) {
 	now := time.Now()
 	user.EmailVerifiedAt = &now
 	user.Email = verificationCode.Metadata["old_email"]
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -534,7 +753,7 @@ func UserResetPassword(ctx *app.HttpContext) {
 		return
 	}
 	user.Password = string(hashedPassword)
-	if err := user.Update(); err != nil {
+	if err := user.UpdateFields(map[string]any{"password": user.Password}); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -547,10 +766,12 @@ func UserResetPassword(ctx *app.HttpContext) {
 	go model.HistoryRecord(user.ID, user, "reset-password", nil)
 	go service.SendMailNewPassword(user, newPassword)
 
-	accesToken := model.NewAccessToken()
-	if err := accesToken.DeleteMany(Filter(Where("user_id", Eq(user.ID)))); err != nil {
-		// ctx.ResponseError(err)
-		app.PrintError("Fail to delete access token: [:user_id] :error", app.E("user_id", user.ID), app.E("error", err.Error()))
+	if err := model.RevokeUserSessions(user.ID); err != nil {
+		ctx.ResponseError(err)
+		return
+	}
+	if err := model.RevokeUserAPITokens(user.ID); err != nil {
+		ctx.ResponseError(err)
 		return
 	}
 
@@ -578,8 +799,10 @@ func UserDestroy(ctx *app.HttpContext) {
 		return
 	}
 
-	accessToken := model.NewAccessToken()
-	if err := accessToken.DeleteMany(Filter(Where("user_id", Eq(user.ID)))); err != nil {
+	if err := model.RevokeUserAPITokens(user.ID); err != nil {
+		app.PrintWarning("Fail to delete api tokens: [:user_id] :error", app.E("user_id", user.ID), app.E("error", err.Error()))
+	}
+	if err := model.RevokeUserSessions(user.ID); err != nil {
 		app.PrintWarning("Fail to delete access token: [:user_id] :token", app.E("user_id", user.ID), app.E("token", err.Error()))
 	}
 
@@ -622,7 +845,7 @@ func Logout(ctx *app.HttpContext) {
 		return
 	}
 
-	if err := accessToken.Delete(); err != nil {
+	if err := accessToken.RevokeSession(); err != nil {
 		ctx.ResponseError(err)
 		return
 	}
@@ -2,10 +2,8 @@ package middleware
 
 import (
 	"strings"
-	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
-	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
 	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
 )
 
@@ -27,27 +25,38 @@ func Auth(next func(ctx *app.HttpContext)) func(ctx *app.HttpContext) {
 		}
 		authToken := parts[1]
 
+		// los tokens de API son opacos (<prefix>.<secreto>) aunque el driver sea jwt
+		if app.JWTEnabled() && strings.Count(authToken, ".") == 2 {
+			accessToken, err := jwtAccessToken(authToken)
+			if err != nil {
+				ctx.ResponseError(err)
+				return
+			}
+			if err := accessToken.TouchJWT(ctx); err != nil {
+				app.PrintError("Failed to update access token", app.Entry{Key: "error", Value: err.Error()})
+			}
+			ctx.Auth = accessToken
+			next(ctx)
+			return
+		}
+
 		accessToken := model.NewAccessToken()
-		if err := accessToken.AggregateOne(Pipeline(
-			Match(Where("token", Eq(authToken))),
-			With("users", "user_id", "_id", "user"),
-			Unwind("$user"),
-		)); err != nil {
-			ctx.ResponseError(app.Errors.Unauthorizedf("Token not found. :error", app.Entry{Key: "error", Value: err.Error()}))
+		if err := accessToken.FindByToken(authToken); err != nil {
+			ctx.ResponseError(err)
 			return
 		}
 
-		if accessToken.ExpiresAt.Before(time.Now()) {
+		if accessToken.Expired() {
 			ctx.ResponseError(app.Errors.Unauthorizedf("Token Expired."))
 			return
 		}
 
-		if accessToken.User.DeletedAt != nil {
+		if accessToken.User == nil || accessToken.User.DeletedAt != nil {
 			ctx.ResponseError(app.Errors.Unauthorizedf("User Inactive or Deleted."))
 			return
 		}
 
-		if err := accessToken.Refresh(); err != nil {
+		if err := accessToken.Touch(ctx); err != nil {
 			app.PrintError("Failed to update access token", app.Entry{Key: "error", Value: err.Error()})
 		}
 
@@ -57,3 +66,23 @@ func Auth(next func(ctx *app.HttpContext)) func(ctx *app.HttpContext) {
 		next(ctx)
 	}
 }
+
+// jwtAccessToken valida la firma y la expiracion sin consultar mongo, los revocados (por jti o por usuario) se buscan en memoria
+func jwtAccessToken(authToken string) (*model.AccessToken, app.Error) {
+	claims, err := app.JWTParse(authToken)
+	if err != nil {
+		return nil, err
+	}
+	if model.IsJWTRevoked(claims.ID) {
+		return nil, app.Errors.Unauthorizedf("Token Revoked.")
+	}
+	accessToken, err := model.AccessTokenFromJWT(authToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	// cambio de contraseña o usuario eliminado, ver model.RevokeUserJWTs
+	if model.IsUserJWTRevoked(accessToken.UserID, accessToken.CreatedAt) {
+		return nil, app.Errors.Unauthorizedf("Token Revoked.")
+	}
+	return accessToken, nil
+}
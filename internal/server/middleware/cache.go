@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+)
+
+// Cache permite que el cliente guarde las respuestas GET exitosas por maxAge, les agrega un ETag
+// y responde 304 si el If-None-Match coincide. Es para datos que casi no cambian (paises, estados, ciudades).
+func Cache(maxAge time.Duration) app.MiddlewareFun {
+
+	return func(next func(ctx *app.HttpContext)) func(ctx *app.HttpContext) {
+
+		return func(ctx *app.HttpContext) {
+
+			if ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+				next(ctx)
+				return
+			}
+
+			// la respuesta se guarda en memoria para calcular el ETag antes de enviarla
+			writer := ctx.Writer
+			buffer := &bufferedWriter{ResponseWriter: writer, status: http.StatusOK}
+			ctx.Writer = buffer
+			next(ctx)
+			ctx.Writer = writer
+
+			if buffer.status != http.StatusOK {
+				writer.WriteHeader(buffer.status)
+				writer.Write(buffer.body.Bytes())
+				return
+			}
+
+			sum := sha256.Sum256(buffer.body.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			header := writer.Header()
+			header.Set("ETag", etag)
+			header.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+			header.Add("Vary", "Accept-Language")
+
+			if etagMatch(ctx.Request.Header.Get("If-None-Match"), etag) {
+				header.Del("Content-Type")
+				writer.WriteHeader(http.StatusNotModified)
+				return
+			}
+			writer.WriteHeader(http.StatusOK)
+			writer.Write(buffer.body.Bytes())
+		}
+	}
+}
+
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// etagMatch compara con la lista del If-None-Match, acepta * y los ETag debiles (W/)
+func etagMatch(ifNoneMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
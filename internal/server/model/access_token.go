@@ -1,8 +1,15 @@
 package model
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
@@ -10,32 +17,77 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
+// largo en caracteres hex de la parte publica del token, sirve para buscarlo y para identificarlo
+const ACCESS_TOKEN_PREFIX_LENGTH = 12
+
+// largo maximo del nombre del dispositivo, si no viene se usa el user agent que puede ser muy largo
+const ACCESS_TOKEN_DEVICE_NAME_LENGTH = 100
+
+// una sesion la crea el login, un token de API lo crea el usuario con un nombre y parte de sus permisos (CI, scripts)
+const ACCESS_TOKEN_TYPE_SESSION = "session"
+const ACCESS_TOKEN_TYPE_API = "api"
+
+// cada cuanto se guarda last_used_at, evita escribir en mongo en cada peticion
+const ACCESS_TOKEN_TOUCH_INTERVAL = time.Minute
+
+// AccessToken en mongo solo se guarda el prefijo y el hash, el token en claro se entrega una vez en el login.
+// El token que recibe el cliente es <prefix>.<secreto>.
+// Cada sesion es un dispositivo, con JWT tambien se guarda para poder listarla y cerrarla.
+// Los tokens de API siempre son opacos aunque el driver sea jwt y pueden no vencer (ExpiresAt en cero, no se guarda).
+// Mongo elimina los tokens vencidos con el indice TTL, la sesion sigue viva mientras su familia de refresh tokens lo este
 type AccessToken struct {
-	ID          bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID      bson.ObjectID `bson:"user_id"       json:"user_id"`
-	User        *User         `bson:"user,omitempty" json:"user,omitempty"`
-	Token       string        `bson:"token"         json:"token"`
-	Permissions []string      `bson:"permissions"   json:"permissions"`
-	CreatedAt   time.Time     `bson:"created_at"    json:"created_at"`
-	ExpiresAt   time.Time     `bson:"expires_at"    json:"expires_at"`
+	ID          bson.ObjectID `bson:"_id,omitempty"          json:"id"`
+	UserID      bson.ObjectID `bson:"user_id"                json:"user_id"`
+	User        *User         `bson:"user,omitempty"         json:"user,omitempty"`
+	Type        string        `bson:"type,omitempty"         json:"type,omitempty"` // sin tipo es una sesion
+	Name        string        `bson:"name,omitempty"         json:"name,omitempty"`
+	Token       string        `bson:"-"                      json:"token,omitempty"` // en claro, solo despues de Generate
+	Prefix      string        `bson:"prefix,omitempty"       json:"prefix,omitempty"`
+	TokenHash   string        `bson:"token_hash,omitempty"   json:"-"`
+	Permissions []string      `bson:"permissions"            json:"permissions"`
+	DeviceName  string        `bson:"device_name,omitempty"  json:"device_name,omitempty"`
+	IP          string        `bson:"ip,omitempty"           json:"ip,omitempty"`
+	UserAgent   string        `bson:"user_agent,omitempty"   json:"user_agent,omitempty"`
+	LastUsedAt  *time.Time    `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt   time.Time     `bson:"created_at"             json:"created_at"`
+	ExpiresAt   time.Time     `bson:"expires_at,omitempty"   json:"expires_at"`
 	app.Odm     `bson:"-" json:"-"`
+
+	userPermissions []string // permisos actuales del usuario, se cargan solo para los tokens de API
 }
 
 func (t *AccessToken) CollectionName() string { return "access_tokens" }
 func (t *AccessToken) GetID() bson.ObjectID   { return t.ID }
 func (t *AccessToken) SetID(id bson.ObjectID) { t.ID = id }
 
-func (t *AccessToken) BeforeCreate() app.Error {
-	t.CreatedAt = time.Now()
-	t.ExpiresAt = t.generateExpiresAt()
-	return nil
+func (t *AccessToken) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "user_id"),
+		app.UniqueIndex(1, "prefix"),
+		app.Index(1, "permissions"),
+		app.Index(1, "expires_at").SetTTL(0), // los tokens de API que no vencen no tienen expires_at
+	}
 }
 
-func (t *AccessToken) BeforeUpdate() app.Error {
-	t.ExpiresAt = t.generateExpiresAt()
+func (t *AccessToken) Relations() []app.ModelRelation {
+	return []app.ModelRelation{
+		app.BelongsTo("user", "users", "user_id"),
+	}
+}
+
+func (t *AccessToken) BeforeCreate() app.Error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	if t.ExpiresAt.IsZero() && !t.IsAPI() {
+		t.ExpiresAt = t.generateExpiresAt()
+	}
 	return nil
 }
 
+// la expiracion no se extiende, para seguir en sesion se usa el refresh token
+func (t *AccessToken) BeforeUpdate() app.Error { return nil }
+
 func NewAccessToken() *AccessToken {
 	token := &AccessToken{}
 	token.Odm.Model = token
@@ -43,26 +95,272 @@ func NewAccessToken() *AccessToken {
 }
 
 func (t *AccessToken) Generate(userID bson.ObjectID, permissions []string) app.Error {
+	if app.JWTEnabled() {
+		return t.generateJWT(userID, permissions)
+	}
+
+	t.Type = ACCESS_TOKEN_TYPE_SESSION
+	return t.generateOpaque(userID, permissions)
+}
+
+// GenerateAPI crea un token de API con nombre, los permisos deben ser parte de los del usuario.
+// Con expiresAt en cero no vence
+func (t *AccessToken) GenerateAPI(userID bson.ObjectID, name string, permissions []string, expiresAt time.Time) app.Error {
+	t.Type = ACCESS_TOKEN_TYPE_API
+	t.Name = name
+	t.ExpiresAt = expiresAt
+	return t.generateOpaque(userID, permissions)
+}
+
+func (t *AccessToken) generateOpaque(userID bson.ObjectID, permissions []string) app.Error {
+	t.UserID = userID
+	t.Prefix = t.generateToken(ACCESS_TOKEN_PREFIX_LENGTH / 2)
+	secret := t.generateToken(32)
+	t.TokenHash = HashToken(secret)
+	t.Token = t.Prefix + "." + secret
+	t.Permissions = permissions
+
+	return t.Create()
+}
+
+func (t *AccessToken) IsAPI() bool {
+	return t.Type == ACCESS_TOKEN_TYPE_API
+}
+
+func (t *AccessToken) Expired() bool {
+	if t.IsAPI() && t.ExpiresAt.IsZero() {
+		return false
+	}
+	return t.ExpiresAt.Before(time.Now())
+}
+
+// FindByToken busca por el prefijo y compara el hash del secreto, carga el usuario sin el password
+func (t *AccessToken) FindByToken(token string) app.Error {
+	invalid := app.Errors.Unauthorizedf("The token is invalid.")
+	prefix, secret, ok := strings.Cut(token, ".")
+	if !ok || len(prefix) != ACCESS_TOKEN_PREFIX_LENGTH || secret == "" {
+		return invalid
+	}
+
+	pipeline := Pipeline(Match(Where("prefix", Eq(prefix))))
+	pipeline = append(pipeline, BelongsTo("users", "user_id", "user", Project(Element("password", 0)))...)
+	if err := t.AggregateOne(pipeline); err != nil {
+		// si mongo falla no es un token invalido, se responde el error para no cerrar la sesion del cliente
+		if err.GetStatus() == http.StatusNotFound {
+			return invalid
+		}
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(t.TokenHash), []byte(HashToken(secret))) != 1 {
+		return invalid
+	}
+	t.Token = token
+	return nil
+}
+
+// HashToken los tokens tienen 256 bits aleatorios, sha256 es suficiente y permite buscarlo por indice
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// SetClient guarda los datos del dispositivo que inicia la sesion, se llama antes de Generate
+func (t *AccessToken) SetClient(ctx *app.HttpContext, deviceName string) {
+	t.DeviceName = deviceName
+	t.IP = ctx.IP()
+	t.UserAgent = ctx.UserAgent()
+	if t.DeviceName == "" {
+		t.DeviceName = t.UserAgent
+	}
+	if name := []rune(t.DeviceName); len(name) > ACCESS_TOKEN_DEVICE_NAME_LENGTH {
+		t.DeviceName = string(name[:ACCESS_TOKEN_DEVICE_NAME_LENGTH])
+	}
+}
+
+// Touch actualiza last_used_at y la ip, como mucho una vez cada ACCESS_TOKEN_TOUCH_INTERVAL
+func (t *AccessToken) Touch(ctx *app.HttpContext) app.Error {
+	if t.LastUsedAt != nil && time.Since(*t.LastUsedAt) < ACCESS_TOKEN_TOUCH_INTERVAL {
+		return nil
+	}
+	now := time.Now()
+	if err := t.UpdateOne(Filter(Where("_id", Eq(t.ID))),
+		Set(Element("last_used_at", now), Element("ip", ctx.IP())),
+	); err != nil {
+		return err
+	}
+	t.LastUsedAt = &now
+	return nil
+}
+
+// ultimo Touch de cada sesion JWT, el token no se lee de mongo asi que no trae last_used_at
+var jwtTouches struct {
+	sync.Mutex
+	at map[bson.ObjectID]time.Time
+}
+
+// TouchJWT igual que Touch para las sesiones JWT, el intervalo se controla en memoria.
+// Los JWT emitidos antes de guardar las sesiones no tienen registro, por eso no se usa Odm.UpdateOne
+func (t *AccessToken) TouchJWT(ctx *app.HttpContext) app.Error {
+	now := time.Now()
+	jwtTouches.Lock()
+	if last, ok := jwtTouches.at[t.ID]; ok && now.Sub(last) < ACCESS_TOKEN_TOUCH_INTERVAL {
+		jwtTouches.Unlock()
+		return nil
+	}
+	if jwtTouches.at == nil {
+		jwtTouches.at = map[bson.ObjectID]time.Time{}
+	}
+	for id, last := range jwtTouches.at {
+		if now.Sub(last) >= ACCESS_TOKEN_TOUCH_INTERVAL {
+			delete(jwtTouches.at, id)
+		}
+	}
+	jwtTouches.at[t.ID] = now
+	jwtTouches.Unlock()
+
+	if _, er := app.DB.Collection(t.CollectionName()).UpdateOne(context.TODO(),
+		Filter(Where("_id", Eq(t.ID))),
+		Set(Element("last_used_at", now), Element("ip", ctx.IP())),
+	); er != nil {
+		return app.Errors.Mongo(er)
+	}
+	t.LastUsedAt = &now
+	return nil
+}
 
+// generateJWT firma el token con el usuario, los permisos y la expiracion.
+// El _id se usa como jti para poder revocarlo, en mongo solo queda la sesion para listarla, el prefijo es el jti
+func (t *AccessToken) generateJWT(userID bson.ObjectID, permissions []string) app.Error {
+	t.ID = bson.NewObjectID()
+	t.Type = ACCESS_TOKEN_TYPE_SESSION
 	t.UserID = userID
-	t.Token = t.generateToken()
 	t.Permissions = permissions
-	// t.CreatedAt = time.Now()
-	// t.ExpiresAt = t.generateExpiresAt()
+	t.CreatedAt = time.Now()
+	t.ExpiresAt = t.generateExpiresAt()
 
+	token, err := app.JWTSign(&app.JWTClaims{
+		ID:          t.ID.Hex(),
+		Subject:     userID.Hex(),
+		IssuedAt:    t.CreatedAt.Unix(),
+		ExpiresAt:   t.ExpiresAt.Unix(),
+		Permissions: permissions,
+	})
+	if err != nil {
+		return err
+	}
+	t.Token = token
+	t.Prefix = t.ID.Hex()
 	return t.Create()
 }
 
-func (t *AccessToken) Refresh() app.Error {
-	// t.Token = t.generateToken()
-	// t.ExpiresAt = t.generateExpiresAt()
-	return t.UpdateOne(Filter(Where("_id", Eq(t.ID))),
-		Set(Element("expires_at", t.generateExpiresAt())),
-	)
+// AccessTokenFromJWT arma el token con los claims ya verificados, sin consultar mongo
+func AccessTokenFromJWT(token string, claims *app.JWTClaims) (*AccessToken, app.Error) {
+	id, er := bson.ObjectIDFromHex(claims.ID)
+	if er != nil {
+		return nil, app.Errors.Unauthorizedf("The token is invalid.")
+	}
+	userID, er := bson.ObjectIDFromHex(claims.Subject)
+	if er != nil {
+		return nil, app.Errors.Unauthorizedf("The token is invalid.")
+	}
+	t := NewAccessToken()
+	t.ID = id
+	t.UserID = userID
+	t.Token = token
+	t.Type = ACCESS_TOKEN_TYPE_SESSION
+	t.Permissions = claims.Permissions
+	t.CreatedAt = time.Unix(claims.IssuedAt, 0)
+	t.ExpiresAt = time.Unix(claims.ExpiresAt, 0)
+	return t, nil
+}
+
+// Revoke invalida el access token, el JWT no se puede borrar asi que se agrega a la lista de revocados
+func (t *AccessToken) Revoke() app.Error {
+	if app.JWTEnabled() && !t.IsAPI() && t.ExpiresAt.After(time.Now()) {
+		if err := RevokeJWT(t.ID.Hex(), t.UserID, t.ExpiresAt); err != nil {
+			return err
+		}
+	}
+	// si ya vencio puede que el TTL lo haya eliminado y los JWT emitidos antes de guardar las sesiones
+	// no tienen registro, no se usa Odm.Delete
+	if _, er := app.DB.Collection(t.CollectionName()).DeleteOne(context.TODO(), Filter(Where("_id", Eq(t.ID)))); er != nil {
+		return app.Errors.Mongo(er)
+	}
+	return nil
+}
+
+// RevokeSession cierra la sesion y revoca su familia de refresh tokens para que no se pueda volver a entrar con ella
+func (t *AccessToken) RevokeSession() app.Error {
+	if err := t.Revoke(); err != nil {
+		return err
+	}
+	refreshToken := NewRefreshToken()
+	if err := refreshToken.FindOne(Filter(Where("access_token_id", Eq(t.ID)))); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+	return RevokeRefreshFamily(refreshToken.FamilyID)
+}
+
+// RevokeUserSessions cierra las sesiones del usuario menos las de except (ids de access tokens), los tokens de API no se tocan.
+// Primero las familias de refresh tokens vivas por que el access token de la sesion puede haber vencido,
+// luego los access tokens que queden. Si se cierran todas con JWT tambien se invalidan los JWT que no tengan sesion guardada
+func RevokeUserSessions(userID bson.ObjectID, except ...bson.ObjectID) app.Error {
+	if app.JWTEnabled() && len(except) == 0 {
+		if err := RevokeUserJWTs(userID); err != nil {
+			return err
+		}
+	}
+	ids := []any{}
+	for _, id := range except {
+		ids = append(ids, id)
+	}
+
+	heads := []*RefreshToken{}
+	if err := NewRefreshToken().Find(&heads, Filter(
+		Where("user_id", Eq(userID)),
+		Where("access_token_id", Nin(ids...)),
+		Where("used_at", Eq(nil)),
+		Where("revoked_at", Eq(nil)),
+	)); err != nil {
+		return err
+	}
+	for _, head := range heads {
+		if err := RevokeRefreshFamily(head.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	return revokeAccessTokens(Filter(
+		Where("user_id", Eq(userID)),
+		Where("type", Ne(ACCESS_TOKEN_TYPE_API)),
+		Where("_id", Nin(ids...)),
+	))
+}
+
+// RevokeUserAPITokens revoca todos los tokens de API del usuario (borrado, cambio de contraseña por un admin)
+func RevokeUserAPITokens(userID bson.ObjectID) app.Error {
+	return revokeAccessTokens(Filter(Where("user_id", Eq(userID)), Where("type", Eq(ACCESS_TOKEN_TYPE_API))))
 }
 
-func (t *AccessToken) generateToken() string {
-	bytes := make([]byte, 32)
+func revokeAccessTokens(filter bson.D) app.Error {
+	tokens := []*AccessToken{}
+	if err := NewAccessToken().Find(&tokens, filter); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		token.Odm.Model = token
+		if err := token.RevokeSession(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *AccessToken) generateToken(size int) string {
+	bytes := make([]byte, size)
 	if _, err := rand.Read(bytes); err != nil {
 		app.PrintWarning("Fail to create access token: " + err.Error())
 	}
@@ -90,11 +388,25 @@ func (t *AccessToken) Can(permissionNames ...string) app.Error {
 	// 	return app.Errors.Forbiddenf("access denied: token expired at :expires_at", app.Entry{Key: "expires_at", Value: result.ExpiresAt})
 	// }
 	// return nil
+
+	// el token de API solo puede lo que el usuario todavia puede, por si le quitaron permisos despues de crearlo
+	if t.IsAPI() && t.userPermissions == nil {
+		user := NewUser()
+		if err := user.FindOneWith(Filter(Where("_id", Eq(t.UserID))), "roles.permissions", "permissions"); err != nil {
+			return app.Errors.Forbidden(err)
+		}
+		t.userPermissions = user.PermissionNames()
+	}
+
 	for _, permission := range t.Permissions {
 		for _, permissionName := range permissionNames {
-			if permission == permissionName {
-				return nil
+			if permission != permissionName {
+				continue
+			}
+			if t.IsAPI() && !slices.Contains(t.userPermissions, permission) {
+				continue
 			}
+			return nil
 		}
 	}
 	return app.Errors.Forbiddenf("access denied: missing permission: :permission", app.Entry{Key: "permission", Value: permissionNames})
@@ -104,10 +416,41 @@ func (t *AccessToken) GetUserID() bson.ObjectID {
 	return t.UserID
 }
 
+// GetTimezone y GetDateFormat implementan app.PreferencesInterface con el usuario del token
+func (t *AccessToken) GetTimezone() string {
+	if t == nil || t.loadUser() != nil || t.User.Profile == nil {
+		return ""
+	}
+	return t.User.Profile.GetPreferences().Timezone
+}
+
+func (t *AccessToken) GetDateFormat() string {
+	if t == nil || t.loadUser() != nil || t.User.Profile == nil {
+		return ""
+	}
+	return t.User.Profile.GetPreferences().DateFormat
+}
+
 func (t *AccessToken) HasRole(roleName ...string) app.Error {
+	if err := t.loadUser(); err != nil {
+		return app.Errors.Forbidden(err)
+	}
 	return t.User.HasRole(roleName...)
 }
 
+// loadUser con JWT el usuario no viene en el token, se carga solo cuando se necesita
+func (t *AccessToken) loadUser() app.Error {
+	if t.User != nil {
+		return nil
+	}
+	user := NewUser()
+	if err := user.FindByID(t.UserID); err != nil {
+		return err
+	}
+	t.User = user
+	return nil
+}
+
 func (t *AccessToken) Anonymous() *AccessToken {
 	var id bson.ObjectID // zero value: "000000000000000000000000"
 	var timeZero time.Time
@@ -21,6 +21,7 @@ type City struct {
 	WikiDataID  string        `bson:"wikiDataId,omitempty"   json:"wikiDataId,omitempty"`
 	CreatedAt   time.Time     `bson:"created_at"             json:"created_at"`
 	UpdatedAt   time.Time     `bson:"updated_at"             json:"updated_at"`
+	DeletedAt   *time.Time    `bson:"deleted_at,omitempty"   json:"deleted_at,omitempty"`
 	app.Odm     `bson:"-" json:"-"`
 }
 
@@ -34,6 +35,26 @@ func (c *City) CollectionName() string { return "cities" }
 func (c *City) GetID() bson.ObjectID   { return c.ID }
 func (c *City) SetID(id bson.ObjectID) { c.ID = id }
 
+func (c *City) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "state_id"),
+		app.Index(1, "country_id"),
+		app.Index(1, "wikiDataId").SetSparse(),
+		app.TextIndex("name", "state_name", "country_name"),
+		app.GeoIndex("location"),
+	}
+}
+
+// SetState asigna el estado y copia los nombres y codigos del estado y del pais
+func (c *City) SetState(state *State) {
+	c.StateID = state.ID
+	c.StateCode = state.Iso2
+	c.StateName = state.Name
+	c.CountryID = state.CountryID
+	c.CountryCode = state.CountryCode
+	c.CountryName = state.CountryName
+}
+
 func (c *City) BeforeCreate() app.Error {
 	c.CreatedAt = time.Now()
 	c.UpdatedAt = time.Now()
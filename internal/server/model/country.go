@@ -1,14 +1,17 @@
 package model
 
 import (
+	"context"
+	"strings"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type Country struct {
-	ID             bson.ObjectID     `bson:"id,omitempty"              json:"id,omitempty"`
+	ID             bson.ObjectID     `bson:"_id,omitempty"             json:"id,omitempty"`
 	Name           string            `bson:"name,omitempty"            json:"name,omitempty"`
 	Iso3           string            `bson:"iso3,omitempty"            json:"iso3,omitempty"`
 	Iso2           string            `bson:"iso2,omitempty"            json:"iso2,omitempty"`
@@ -30,6 +33,7 @@ type Country struct {
 	EmojiU         string            `bson:"emojiU,omitempty"          json:"emojiU,omitempty"`
 	CreatedAt      time.Time         `bson:"created_at"                json:"created_at"`
 	UpdatedAt      time.Time         `bson:"updated_at"                json:"updated_at"`
+	DeletedAt      *time.Time        `bson:"deleted_at,omitempty"      json:"deleted_at,omitempty"`
 	app.Odm        `bson:"-" json:"-"`
 }
 
@@ -66,6 +70,56 @@ func (c *Country) CollectionName() string { return "countries" }
 func (c *Country) GetID() bson.ObjectID   { return c.ID }
 func (c *Country) SetID(id bson.ObjectID) { c.ID = id }
 
+func (c *Country) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "name"),
+		app.TextIndex("name"),
+		app.GeoIndex("location"),
+	}
+}
+
+// Localize traduce el nombre del pais, la region y la subregion al primer idioma de la lista
+// que tenga traduccion (ej: "es-CO", "es"), en ingles se deja el nombre original
+func (c *Country) Localize(locales ...string) {
+	c.Name = translatedName(c.Translations, c.Name, locales)
+	c.Region.Name = translatedName(c.Region.Translations, c.Region.Name, locales)
+	c.Subregion.Name = translatedName(c.Subregion.Translations, c.Subregion.Name, locales)
+}
+
+func translatedName(translations map[string]string, name string, locales []string) string {
+	for _, locale := range locales {
+		language, _, _ := strings.Cut(locale, "-")
+		if strings.EqualFold(language, "en") {
+			return name
+		}
+		for _, key := range []string{locale, language} {
+			for k, v := range translations {
+				if strings.EqualFold(k, key) && v != "" {
+					return v
+				}
+			}
+		}
+	}
+	return name
+}
+
+// SyncChildren copia el nombre y el codigo del pais a sus estados y ciudades
+func (c *Country) SyncChildren() app.Error {
+	for _, collection := range []string{NewState().CollectionName(), NewCity().CollectionName()} {
+		_, er := app.DB.Collection(collection).UpdateMany(context.TODO(),
+			Filter(Where("country_id", Eq(c.ID))),
+			Set(
+				Element("country_code", c.Iso2),
+				Element("country_name", c.Name),
+			),
+		)
+		if er != nil {
+			return app.Errors.Mongo(er)
+		}
+	}
+	return nil
+}
+
 func (c *Country) BeforeCreate() app.Error {
 	c.CreatedAt = time.Now()
 	c.UpdatedAt = time.Now()
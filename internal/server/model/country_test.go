@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestTranslatedName(t *testing.T) {
+	translations := map[string]string{"es": "Alemania", "pt-BR": "Alemanha", "fr": "", "KR": "독일"}
+	tests := []struct {
+		name    string
+		locales []string
+		want    string
+	}{
+		{"sin idiomas", nil, "Germany"},
+		{"idioma", []string{"es"}, "Alemania"},
+		{"region cae al idioma", []string{"es-CO"}, "Alemania"},
+		{"region exacta", []string{"pt-BR"}, "Alemanha"},
+		{"region sin traduccion ni idioma", []string{"pt-PT"}, "Germany"},
+		{"sin mayusculas", []string{"kr"}, "독일"},
+		{"traduccion vacia sigue con el siguiente", []string{"fr", "es"}, "Alemania"},
+		{"sin traduccion sigue con el siguiente", []string{"de", "es"}, "Alemania"},
+		{"ingles es el nombre original", []string{"en-US", "es"}, "Germany"},
+		{"ninguno traducido", []string{"ja", "zh"}, "Germany"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := translatedName(translations, "Germany", tt.locales); got != tt.want {
+				t.Errorf("translatedName(%v) = %s, want %s", tt.locales, got, tt.want)
+			}
+		})
+	}
+}
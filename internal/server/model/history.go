@@ -37,6 +37,15 @@ func (a *History) CollectionName() string { return "histories" }
 func (a *History) GetID() bson.ObjectID   { return a.ID }
 func (a *History) SetID(id bson.ObjectID) { a.ID = id }
 
+func (a *History) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "user_id"),
+		app.Index(1, "document_id"),
+		app.Index(1, "collection"),
+		app.Index(-1, "occurred_at"),
+	}
+}
+
 func (a *History) BeforeCreate() app.Error {
 	a.OcurredAt = time.Now()
 	return nil
@@ -64,22 +73,22 @@ func HistoryRecord(userID bson.ObjectID, collection app.Model, action string, ol
 }
 
 func HistoryManyRecords(userID bson.ObjectID, collection app.Model, action string, old ...any) {
-	history := &History{}
-	history.Odm.Model = history
-	data := make([]History, len(old))
+	operations := make([]app.BulkOperation, 0, len(old))
 	for _, change := range old {
 		if change == nil {
 			change = bson.M{}
 		}
-		data = append(data, History{
+		history := &History{
 			UserID:     userID,
 			DocumentID: collection.GetID(),
 			Collection: collection.CollectionName(),
 			Action:     action,
 			Old:        change,
-		})
+		}
+		history.Odm.Model = history
+		operations = append(operations, app.BulkInsert(history))
 	}
-	if err := history.CreateMany(data); err != nil {
+	if _, err := NewHistory().BulkWrite(operations, &app.BulkOptions{Ordered: false}); err != nil {
 		app.PrintError("Failed to create activity record", app.Entry{Key: "error", Value: err})
 	}
 }
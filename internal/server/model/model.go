@@ -0,0 +1,21 @@
+package model
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+// registre aca los modelos para que las herramientas (validaciones, exportaciones, papelera)
+// los puedan encontrar por el nombre de la coleccion
+func init() {
+	app.RegisterModel(NewUser)
+	app.RegisterModel(NewRole)
+	app.RegisterModel(NewPermission)
+	app.RegisterModel(NewAccessToken)
+	app.RegisterModel(NewRevokedToken)
+	app.RegisterModel(NewRefreshToken)
+	app.RegisterModel(NewVerificationCode)
+	app.RegisterModel(NewHistory)
+	app.RegisterModel(NewTrash)
+	app.RegisterModel(NewSystemLog)
+	app.RegisterModel(NewCountry)
+	app.RegisterModel(NewState)
+	app.RegisterModel(NewCity)
+}
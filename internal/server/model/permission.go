@@ -21,6 +21,12 @@ func (p *Permission) CollectionName() string { return "permissions" }
 func (p *Permission) GetID() bson.ObjectID   { return p.ID }
 func (p *Permission) SetID(id bson.ObjectID) { p.ID = id }
 
+func (p *Permission) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "name"),
+	}
+}
+
 func (p *Permission) BeforeCreate() app.Error { return nil }
 
 func (p *Permission) BeforeUpdate() app.Error { return nil }
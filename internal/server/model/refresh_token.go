@@ -0,0 +1,167 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// RefreshToken es la credencial de larga duracion para pedir un access token nuevo.
+// Solo se guarda el hash, el token en claro se entrega una vez al cliente.
+// Cada refresh crea uno nuevo en la misma familia y marca el anterior como usado,
+// si llega uno usado alguien lo copio y se revoca toda la familia.
+type RefreshToken struct {
+	ID              bson.ObjectID `bson:"_id,omitempty"         json:"id"`
+	UserID          bson.ObjectID `bson:"user_id"               json:"user_id"`
+	FamilyID        bson.ObjectID `bson:"family_id"             json:"family_id"`
+	TokenHash       string        `bson:"token_hash"            json:"-"`
+	AccessTokenID   bson.ObjectID `bson:"access_token_id"       json:"access_token_id"`
+	AccessExpiresAt time.Time     `bson:"access_expires_at"     json:"access_expires_at"`
+	DeviceName      string        `bson:"device_name,omitempty" json:"device_name,omitempty"`
+	IP              string        `bson:"ip,omitempty"          json:"ip,omitempty"`
+	UserAgent       string        `bson:"user_agent,omitempty"  json:"user_agent,omitempty"`
+	UsedAt          *time.Time    `bson:"used_at,omitempty"     json:"used_at,omitempty"`
+	RevokedAt       *time.Time    `bson:"revoked_at,omitempty"  json:"revoked_at,omitempty"`
+	CreatedAt       time.Time     `bson:"created_at"            json:"created_at"`
+	ExpiresAt       time.Time     `bson:"expires_at"            json:"expires_at"`
+	Token           string        `bson:"-"                     json:"-"` // en claro, solo despues de Generate
+	app.Odm         `bson:"-" json:"-"`
+}
+
+func NewRefreshToken() *RefreshToken {
+	token := &RefreshToken{}
+	token.Odm.Model = token
+	return token
+}
+
+func (r *RefreshToken) CollectionName() string { return "refresh_tokens" }
+func (r *RefreshToken) GetID() bson.ObjectID   { return r.ID }
+func (r *RefreshToken) SetID(id bson.ObjectID) { r.ID = id }
+
+func (r *RefreshToken) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "token_hash"),
+		app.Index(1, "family_id"),
+		app.Index(1, "user_id"),
+		app.Index(1, "access_token_id"),
+		app.Index(1, "expires_at").SetTTL(0),
+	}
+}
+
+func (r *RefreshToken) BeforeCreate() app.Error {
+	r.CreatedAt = time.Now()
+	if r.ExpiresAt.IsZero() {
+		r.ExpiresAt = time.Now().Add(time.Duration(app.Env.REFRESH_TOKEN_LIFETIME) * time.Minute)
+	}
+	return nil
+}
+
+func (r *RefreshToken) BeforeUpdate() app.Error { return nil }
+
+// Generate crea el refresh token para el access token, con familyID en cero empieza una familia nueva (login)
+func (r *RefreshToken) Generate(accessToken *AccessToken, familyID bson.ObjectID) app.Error {
+	bytes := make([]byte, 32)
+	if _, er := rand.Read(bytes); er != nil {
+		return app.Errors.InternalServerError(er)
+	}
+	if familyID.IsZero() {
+		familyID = bson.NewObjectID()
+	}
+	r.Token = hex.EncodeToString(bytes)
+	r.TokenHash = HashToken(r.Token)
+	r.UserID = accessToken.UserID
+	r.FamilyID = familyID
+	r.AccessTokenID = accessToken.ID
+	r.AccessExpiresAt = accessToken.ExpiresAt
+	r.DeviceName = accessToken.DeviceName
+	r.IP = accessToken.IP
+	r.UserAgent = accessToken.UserAgent
+	return r.Create()
+}
+
+// Rotate valida el refresh token en claro y lo marca como usado, retorna el registro para crear el siguiente.
+// Si ya estaba usado o revocado se revoca toda la familia.
+func (r *RefreshToken) Rotate(token string) app.Error {
+	invalid := app.Errors.Unauthorizedf("The refresh token is invalid.")
+	if err := r.FindOne(Filter(Where("token_hash", Eq(HashToken(token))))); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return invalid
+		}
+		return err
+	}
+	if r.UsedAt != nil || r.RevokedAt != nil {
+		return r.reuseDetected()
+	}
+	if r.ExpiresAt.Before(time.Now()) {
+		return app.Errors.Unauthorizedf("The refresh token has expired.")
+	}
+
+	// el filtro con used_at nil evita que dos peticiones con el mismo token roten a la vez
+	now := time.Now()
+	if err := r.UpdateOne(
+		Filter(Where("_id", Eq(r.ID)), Where("used_at", Eq(nil))),
+		Set(Element("used_at", now)),
+	); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return r.reuseDetected()
+		}
+		return err
+	}
+	r.UsedAt = &now
+	return nil
+}
+
+func (r *RefreshToken) reuseDetected() app.Error {
+	app.PrintWarning("Refresh token reuse detected, revoking family :family", app.E("family", r.FamilyID.Hex()), app.E("user", r.UserID.Hex()))
+	if err := RevokeRefreshFamily(r.FamilyID); err != nil {
+		return err
+	}
+	go HistoryRecord(r.UserID, r, "refresh-token-reuse", nil)
+	return app.Errors.Unauthorizedf("The refresh token was already used, the session has been closed.")
+}
+
+// RevokeRefreshFamily revoca los refresh tokens de la familia y los access tokens que se emitieron con ellos.
+// Primero los refresh tokens, si algo falla despues la familia ya no se puede usar para entrar
+func RevokeRefreshFamily(familyID bson.ObjectID) app.Error {
+	tokens := []*RefreshToken{}
+	if err := NewRefreshToken().Find(&tokens, Filter(Where("family_id", Eq(familyID)))); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, er := app.DB.Collection(NewRefreshToken().CollectionName()).UpdateMany(context.TODO(),
+		Filter(Where("family_id", Eq(familyID)), Where("revoked_at", Eq(nil))),
+		Set(Element("revoked_at", now)),
+	)
+	if er != nil {
+		return app.Errors.Mongo(er)
+	}
+
+	accessIDs := []any{}
+	for _, t := range tokens {
+		if t.AccessExpiresAt.Before(now) {
+			continue
+		}
+		if app.JWTEnabled() {
+			if err := RevokeJWT(t.AccessTokenID.Hex(), t.UserID, t.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+		accessIDs = append(accessIDs, t.AccessTokenID)
+	}
+	if len(accessIDs) > 0 {
+		// puede que el usuario ya haya cerrado alguna de las sesiones, no se usa Odm.DeleteMany
+		if _, er := app.DB.Collection(NewAccessToken().CollectionName()).DeleteMany(context.TODO(),
+			Filter(Where("_id", In(accessIDs...))),
+		); er != nil {
+			return app.Errors.Mongo(er)
+		}
+	}
+	return nil
+}
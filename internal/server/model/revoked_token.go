@@ -0,0 +1,200 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// cada cuanto se recarga la lista de tokens revocados desde mongo (para las otras instancias)
+const REVOKED_TOKENS_SYNC = time.Minute
+
+// tiempo maximo de la consulta de la recarga, si mongo no responde se sigue con la lista anterior
+const REVOKED_TOKENS_SYNC_TIMEOUT = 5 * time.Second
+
+// los registros con este prefijo en el jti revocan todos los JWT del usuario emitidos antes de su created_at
+// (cambio o recuperacion de contraseña, usuario eliminado). Vencen cuando ya no queda ningun JWT anterior vigente
+const REVOKED_USER_PREFIX = "user:"
+
+// RevokedToken es la lista de JWT revocados antes de vencer, mongo los elimina cuando vencen
+type RevokedToken struct {
+	ID        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	JTI       string        `bson:"jti"           json:"jti"`
+	UserID    bson.ObjectID `bson:"user_id"       json:"user_id"`
+	ExpiresAt time.Time     `bson:"expires_at"    json:"expires_at"`
+	CreatedAt time.Time     `bson:"created_at"    json:"created_at"`
+	app.Odm   `bson:"-" json:"-"`
+}
+
+func NewRevokedToken() *RevokedToken {
+	token := &RevokedToken{}
+	token.Odm.Model = token
+	return token
+}
+
+func (r *RevokedToken) CollectionName() string { return "revoked_tokens" }
+func (r *RevokedToken) GetID() bson.ObjectID   { return r.ID }
+func (r *RevokedToken) SetID(id bson.ObjectID) { r.ID = id }
+
+func (r *RevokedToken) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "jti"),
+		app.Index(1, "expires_at").SetTTL(0),
+	}
+}
+
+func (r *RevokedToken) BeforeCreate() app.Error {
+	r.CreatedAt = time.Now()
+	return nil
+}
+
+func (r *RevokedToken) BeforeUpdate() app.Error { return nil }
+
+// la lista se consulta en memoria para no ir a mongo en cada peticion
+var revokedTokens struct {
+	sync.RWMutex
+	jti      map[string]time.Time
+	users    map[bson.ObjectID]time.Time // user_id => los JWT emitidos antes no sirven
+	syncedAt time.Time
+	syncing  bool // solo una peticion recarga a la vez
+}
+
+// RevokeJWT agrega el jti a la lista hasta que el token venza.
+// Es un upsert para que revocar dos veces el mismo token (logout y cierre de la familia) no choque con el indice unico
+func RevokeJWT(jti string, userID bson.ObjectID, expiresAt time.Time) app.Error {
+	_, er := app.DB.Collection(NewRevokedToken().CollectionName()).UpdateOne(context.TODO(),
+		Filter(Where("jti", Eq(jti))),
+		SetOnInsert(
+			Element("user_id", userID),
+			Element("expires_at", expiresAt),
+			Element("created_at", time.Now()),
+		),
+		options.UpdateOne().SetUpsert(true),
+	)
+	if er != nil {
+		return app.Errors.Mongo(er)
+	}
+
+	revokedTokens.Lock()
+	if revokedTokens.jti == nil {
+		revokedTokens.jti = map[string]time.Time{}
+	}
+	revokedTokens.jti[jti] = expiresAt
+	revokedTokens.Unlock()
+	return nil
+}
+
+// RevokeUserJWTs invalida todos los JWT del usuario emitidos hasta ahora, incluidos los que ya no tienen sesion guardada
+func RevokeUserJWTs(userID bson.ObjectID) app.Error {
+	now := time.Now()
+	_, er := app.DB.Collection(NewRevokedToken().CollectionName()).UpdateOne(context.TODO(),
+		Filter(Where("jti", Eq(REVOKED_USER_PREFIX+userID.Hex()))),
+		Set(
+			Element("user_id", userID),
+			Element("created_at", now),
+			Element("expires_at", now.Add(time.Duration(app.Env.SESSION_LIFETIME)*time.Minute)),
+		),
+		options.UpdateOne().SetUpsert(true),
+	)
+	if er != nil {
+		return app.Errors.Mongo(er)
+	}
+
+	revokedTokens.Lock()
+	if revokedTokens.users == nil {
+		revokedTokens.users = map[bson.ObjectID]time.Time{}
+	}
+	revokedTokens.users[userID] = now
+	revokedTokens.Unlock()
+	return nil
+}
+
+// IsJWTRevoked busca en la lista en memoria, se recarga desde mongo cada REVOKED_TOKENS_SYNC
+func IsJWTRevoked(jti string) bool {
+	syncStaleRevokedTokens()
+	revokedTokens.RLock()
+	_, revoked := revokedTokens.jti[jti]
+	revokedTokens.RUnlock()
+	return revoked
+}
+
+// IsUserJWTRevoked indica si el JWT se emitio antes de RevokeUserJWTs. iat va en segundos, un token del mismo segundo
+// que la revocacion tambien se rechaza por que no se sabe si se emitio antes o despues
+func IsUserJWTRevoked(userID bson.ObjectID, issuedAt time.Time) bool {
+	syncStaleRevokedTokens()
+	revokedTokens.RLock()
+	revokedAt, ok := revokedTokens.users[userID]
+	revokedTokens.RUnlock()
+	return ok && !issuedAt.After(revokedAt.Truncate(time.Second))
+}
+
+// syncStaleRevokedTokens recarga la lista si ya paso REVOKED_TOKENS_SYNC, la consulta va sin el lock
+// para no frenar las demas peticiones mientras mongo responde
+func syncStaleRevokedTokens() {
+	revokedTokens.Lock()
+	if revokedTokens.syncing || time.Since(revokedTokens.syncedAt) <= REVOKED_TOKENS_SYNC {
+		revokedTokens.Unlock()
+		return
+	}
+	// si mongo falla se conserva la lista anterior y se intenta en el siguiente periodo
+	revokedTokens.syncing = true
+	revokedTokens.syncedAt = time.Now()
+	revokedTokens.Unlock()
+
+	err := syncRevokedTokens()
+
+	revokedTokens.Lock()
+	revokedTokens.syncing = false
+	revokedTokens.Unlock()
+	if err != nil {
+		app.PrintError("Fail to sync revoked tokens :error", app.E("error", err.Error()))
+	}
+}
+
+func syncRevokedTokens() app.Error {
+	ctx, cancel := context.WithTimeout(context.Background(), REVOKED_TOKENS_SYNC_TIMEOUT)
+	defer cancel()
+
+	now := time.Now()
+	cursor, er := app.DB.Collection(NewRevokedToken().CollectionName()).Find(ctx, Filter(Where("expires_at", Gt(now))))
+	if er != nil {
+		return app.Errors.Mongo(er)
+	}
+	tokens := []*RevokedToken{}
+	if er := cursor.All(ctx, &tokens); er != nil {
+		return app.Errors.Mongo(er)
+	}
+	jti := make(map[string]time.Time, len(tokens))
+	users := map[bson.ObjectID]time.Time{}
+	for _, t := range tokens {
+		if strings.HasPrefix(t.JTI, REVOKED_USER_PREFIX) {
+			users[t.UserID] = t.CreatedAt
+			continue
+		}
+		jti[t.JTI] = t.ExpiresAt
+	}
+
+	revokedTokens.Lock()
+	defer revokedTokens.Unlock()
+	// las revocaciones de esta instancia que llegaron durante la consulta se conservan, una revocacion no se deshace
+	for key, expiresAt := range revokedTokens.jti {
+		if _, ok := jti[key]; !ok && expiresAt.After(now) {
+			jti[key] = expiresAt
+		}
+	}
+	lifetime := time.Duration(app.Env.SESSION_LIFETIME) * time.Minute
+	for userID, revokedAt := range revokedTokens.users {
+		if revokedAt.After(users[userID]) && revokedAt.Add(lifetime).After(now) {
+			users[userID] = revokedAt
+		}
+	}
+	revokedTokens.jti = jti
+	revokedTokens.users = users
+	return nil
+}
@@ -2,7 +2,6 @@ package model
 
 import (
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
-	"github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -24,10 +23,18 @@ func (r *Role) CollectionName() string { return "roles" }
 func (r *Role) GetID() bson.ObjectID   { return r.ID }
 func (r *Role) SetID(id bson.ObjectID) { r.ID = id }
 
+func (r *Role) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "name"),
+	}
+}
+
+func (r *Role) Relations() []app.ModelRelation {
+	return []app.ModelRelation{
+		app.ManyToMany("permissions", "permissions", "permission_ids"),
+	}
+}
+
 func (r *Role) BeforeCreate() app.Error { return nil }
 
 func (r *Role) BeforeUpdate() app.Error { return nil }
-
-func (r *Role) WithPermissions() bson.D {
-	return qb.ManyToMany("permissions", "permission_ids")
-}
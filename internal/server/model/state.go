@@ -1,9 +1,11 @@
 package model
 
 import (
+	"context"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
 	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
@@ -23,6 +25,7 @@ type State struct {
 	Timezone    string        `bson:"timezone,omitempty"     json:"timezone,omitempty"`
 	CreatedAt   time.Time     `bson:"created_at"             json:"created_at"`
 	UpdatedAt   time.Time     `bson:"updated_at"             json:"updated_at"`
+	DeletedAt   *time.Time    `bson:"deleted_at,omitempty"   json:"deleted_at,omitempty"`
 	app.Odm     `bson:"-" json:"-"`
 }
 
@@ -36,6 +39,40 @@ func (s *State) CollectionName() string { return "states" }
 func (s *State) GetID() bson.ObjectID   { return s.ID }
 func (s *State) SetID(id bson.ObjectID) { s.ID = id }
 
+func (s *State) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "country_id"),
+		app.TextIndex("name", "country_name"),
+		app.GeoIndex("location"),
+	}
+}
+
+// SetCountry asigna el pais y copia su nombre y codigo
+func (s *State) SetCountry(country *Country) {
+	s.CountryID = country.ID
+	s.CountryCode = country.Iso2
+	s.CountryName = country.Name
+}
+
+// SyncCities copia el nombre y los codigos del estado a sus ciudades, los ids no cambian
+// asi que las referencias (ej: profile.city_id) siguen siendo validas
+func (s *State) SyncCities() app.Error {
+	_, er := app.DB.Collection(NewCity().CollectionName()).UpdateMany(context.TODO(),
+		Filter(Where("state_id", Eq(s.ID))),
+		Set(
+			Element("state_code", s.Iso2),
+			Element("state_name", s.Name),
+			Element("country_id", s.CountryID),
+			Element("country_code", s.CountryCode),
+			Element("country_name", s.CountryName),
+		),
+	)
+	if er != nil {
+		return app.Errors.Mongo(er)
+	}
+	return nil
+}
+
 func (s *State) BeforeCreate() app.Error {
 	s.CreatedAt = time.Now()
 	s.UpdatedAt = time.Now()
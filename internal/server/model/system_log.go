@@ -8,7 +8,7 @@ import (
 )
 
 type SystemLog struct {
-	ID       bson.ObjectID     `bson:"id,omitempty"       json:"id"`
+	ID       bson.ObjectID     `bson:"_id,omitempty"      json:"id"`
 	Time     time.Time         `bson:"time,omitempty"     json:"time"`
 	Level    string            `bson:"level,omitempty"    json:"level"`
 	Message  string            `bson:"message"            json:"message"`
@@ -29,6 +29,12 @@ func (l *SystemLog) CollectionName() string { return "system_logs" }
 func (l *SystemLog) GetID() bson.ObjectID   { return l.ID }
 func (l *SystemLog) SetID(id bson.ObjectID) { l.ID = id }
 
+func (l *SystemLog) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "time"),
+	}
+}
+
 func (l *SystemLog) BeforeCreate() app.Error { return nil }
 
 func (l *SystemLog) BeforeUpdate() app.Error { return nil }
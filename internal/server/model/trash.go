@@ -27,6 +27,14 @@ func (t *Trash) CollectionName() string { return "trash" }
 func (t *Trash) GetID() bson.ObjectID   { return t.ID }
 func (t *Trash) SetID(id bson.ObjectID) { t.ID = id }
 
+func (t *Trash) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.Index(1, "document._id"),
+		app.Index(1, "collection"),
+		app.Index(-1, "deleted_at"),
+	}
+}
+
 func (t *Trash) BeforeCreate() app.Error {
 	t.DeletedAt = time.Now()
 	return nil
@@ -53,6 +61,19 @@ func (t *Trash) RestoreByHexID(m app.Model, id string) app.Error {
 	return t.Restore(m, oid)
 }
 
+// RestoreFromCollection restaura un documento usando el modelo registrado para la coleccion
+func (t *Trash) RestoreFromCollection(collection string, id bson.ObjectID) (app.Model, app.Error) {
+	schema, ok := app.GetModel(collection)
+	if !ok {
+		return nil, app.Errors.Restoref("The collection :collection is not registered", app.E("collection", collection))
+	}
+	m := schema.New()
+	if err := t.Restore(m, id); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (t *Trash) Restore(m app.Model, id bson.ObjectID) app.Error {
 	ctx := context.TODO()
 	cursor, er := app.DB.Collection(t.CollectionName()).Aggregate(ctx, Pipeline(
@@ -60,9 +81,9 @@ func (t *Trash) Restore(m app.Model, id bson.ObjectID) app.Error {
 			Where("document._id", Eq(id)),
 			Where("collection", Eq(m.CollectionName())),
 		),
-		bson.D{{"$sort", bson.D{{"deleted_at", -1}}}},
-		bson.D{{"$limit", 1}},
-		bson.D{{"$replaceRoot", bson.D{{"newRoot", "$document"}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "deleted_at", Value: -1}}}},
+		bson.D{{Key: "$limit", Value: 1}},
+		bson.D{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$document"}}}},
 	))
 	if er != nil {
 		return app.Errors.Mongo(er)
@@ -74,7 +95,7 @@ func (t *Trash) Restore(m app.Model, id bson.ObjectID) app.Error {
 			return app.Errors.Mongo(er)
 		}
 	} else {
-		return app.Errors.NoDocumentsf("No documents matched for restore :collection [:model::id]", app.E("id", id), app.E("model", m.CollectionName()), app.E("collection", "activity"))
+		return app.Errors.NoDocumentsf("No documents matched for restore :collection [:model::id]", app.E("id", id), app.E("model", m.CollectionName()), app.E("collection", t.CollectionName()))
 	}
 	if err := m.Create(); err != nil {
 		return err
@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
@@ -22,6 +23,7 @@ type User struct {
 	PermissionIDs   []bson.ObjectID `bson:"permission_ids"              json:"-"`
 	Permissions     []*Permission   `bson:"permissions,omitempty"       json:"permissions,omitempty"` // manyToMany
 	EmailVerifiedAt *time.Time      `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+	TwoFactor       *TwoFactor      `bson:"two_factor,omitempty"        json:"two_factor,omitempty"`
 	CreatedAt       time.Time       `bson:"created_at"                  json:"created_at"`
 	UpdatedAt       time.Time       `bson:"updated_at"                  json:"updated_at"`
 	DeletedAt       *time.Time      `bson:"deleted_at,omitempty"        json:"deleted_at,omitempty"`
@@ -29,13 +31,13 @@ type User struct {
 }
 
 type Profile struct {
-	Avatar          string         `bson:"avatar,omitempty"           json:"avatar,omitempty"`
-	FullName        string         `bson:"full_name,omitempty"        json:"full_name,omitempty"`
-	Nickname        string         `bson:"nickname"                   json:"nickname"`
-	PhoneNumber     string         `bson:"phone_number,omitempty"     json:"phone_number,omitempty"`
-	DiscordUsername string         `bson:"discord_username,omitempty" json:"discord_username,omitempty"`
-	CityID          bson.ObjectID  `bson:"city_id"                    json:"city_id"`
-	Preferences     map[string]any `bson:"preferences,omitempty"      json:"preferences,omitempty"`
+	Avatar          string        `bson:"avatar,omitempty"           json:"avatar,omitempty"`
+	FullName        string        `bson:"full_name,omitempty"        json:"full_name,omitempty"`
+	Nickname        string        `bson:"nickname"                   json:"nickname"`
+	PhoneNumber     string        `bson:"phone_number,omitempty"     json:"phone_number,omitempty"`
+	DiscordUsername string        `bson:"discord_username,omitempty" json:"discord_username,omitempty"`
+	CityID          bson.ObjectID `bson:"city_id"                    json:"city_id"`
+	Preferences     *Preferences  `bson:"preferences,omitempty"      json:"preferences,omitempty"`
 }
 
 func NewUser() *User {
@@ -49,6 +51,22 @@ func (u *User) CollectionName() string { return "users" }
 func (u *User) GetID() bson.ObjectID   { return u.ID }
 func (u *User) SetID(id bson.ObjectID) { u.ID = id }
 
+func (u *User) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "email"),
+		app.UniqueIndex(1, "profile.nickname"),
+		app.Index(1, "deleted_at").SetSparse(),
+	}
+}
+
+func (u *User) Relations() []app.ModelRelation {
+	return []app.ModelRelation{
+		app.ManyToMany("roles", "roles", "role_ids"),
+		app.ManyToMany("permissions", "permissions", "permission_ids"),
+		app.HasMany("access_tokens", "access_tokens", "user_id"),
+	}
+}
+
 func (u *User) BeforeCreate() app.Error {
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
@@ -60,21 +78,6 @@ func (u *User) BeforeUpdate() app.Error {
 	return nil
 }
 
-// manyToMany
-func (u *User) WithRoles() bson.D {
-	return ManyToMany("roles", "role_ids", ManyToMany("permissions", "permission_ids"))
-}
-
-// manyToMany
-func (u *User) WhithPermissions() bson.D {
-	return ManyToMany("permissions", "permission_ids")
-}
-
-// hasMany
-func (u *User) WithTokens() bson.D {
-	return HasMany("access_tokens", "user_id")
-}
-
 func (u *User) HasRole(roleName ...string) app.Error {
 	role := NewRole()
 	err := role.FindOne(Document(
@@ -90,6 +93,24 @@ func (u *User) HasRole(roleName ...string) app.Error {
 	return nil
 }
 
+// PermissionNames une los permisos de los roles y los directos sin repetir, necesita cargados roles.permissions y permissions
+func (u *User) PermissionNames() []string {
+	permissions := []string{}
+	for _, role := range u.Roles {
+		for _, permission := range role.Permissions {
+			if !slices.Contains(permissions, permission.Name) {
+				permissions = append(permissions, permission.Name)
+			}
+		}
+	}
+	for _, permission := range u.Permissions {
+		if !slices.Contains(permissions, permission.Name) {
+			permissions = append(permissions, permission.Name)
+		}
+	}
+	return permissions
+}
+
 func (u *User) Can(permissionName string) app.Error {
 	usersCol := app.DB.Collection(u.CollectionName())
 
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Preferences se guarda en profile.preferences
+type Preferences struct {
+	Timezone      string                  `bson:"timezone,omitempty"    json:"timezone,omitempty"`
+	Locale        string                  `bson:"locale,omitempty"      json:"locale,omitempty"`
+	DateFormat    string                  `bson:"date_format,omitempty" json:"date_format,omitempty"`
+	Notifications NotificationPreferences `bson:"notifications"         json:"notifications"`
+}
+
+type NotificationPreferences struct {
+	Email     bool `bson:"email"     json:"email"`
+	Push      bool `bson:"push"      json:"push"`
+	Marketing bool `bson:"marketing" json:"marketing"`
+}
+
+// DefaultPreferences la zona horaria se toma de la ciudad, si no se encuentra queda en UTC
+func DefaultPreferences(cityID bson.ObjectID) *Preferences {
+	preferences := &Preferences{
+		Timezone:   "UTC",
+		Locale:     app.Env.APP_LOCALE,
+		DateFormat: app.DATE_FORMAT_DEFAULT,
+		Notifications: NotificationPreferences{
+			Email: true,
+			Push:  true,
+		},
+	}
+	if cityID.IsZero() {
+		return preferences
+	}
+	city := NewCity()
+	if err := city.FindByID(cityID); err == nil && city.Timezone != "" {
+		preferences.Timezone = city.Timezone
+	}
+	return preferences
+}
+
+// GetPreferences retorna las preferencias del perfil o las de su ciudad si no tiene
+func (p *Profile) GetPreferences() *Preferences {
+	if p.Preferences == nil {
+		return DefaultPreferences(p.CityID)
+	}
+	return p.Preferences
+}
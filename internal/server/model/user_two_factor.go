@@ -0,0 +1,172 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
+)
+
+// cantidad de codigos de recuperacion que se entregan al activar el 2FA
+const TWO_FACTOR_RECOVERY_CODES = 8
+
+// tipo del VerificationCode que queda pendiente entre la contraseña y el codigo TOTP
+const TWO_FACTOR_LOGIN = "two-factor-login"
+
+// minutos que tiene el usuario para enviar el codigo despues de la contraseña
+const TWO_FACTOR_LOGIN_LIFETIME = 5
+
+// intentos con codigo equivocado por usuario antes de bloquear el segundo paso del login
+const TWO_FACTOR_MAX_ATTEMPTS = 5
+
+// minutos que dura el bloqueo despues de TWO_FACTOR_MAX_ATTEMPTS
+const TWO_FACTOR_ATTEMPTS_WINDOW = 15
+
+// bytes aleatorios de cada codigo de recuperacion, 128 bits no se pueden adivinar ni sacar del hash sha256
+const TWO_FACTOR_RECOVERY_CODE_BYTES = 16
+
+// TwoFactor el secreto se guarda cifrado con APP_KEY y los codigos de recuperacion con hash, ninguno sale en el json.
+// Sin ConfirmedAt el usuario lo inicio pero no ha enviado el primer codigo, el login no lo pide
+type TwoFactor struct {
+	Secret        string     `bson:"secret"                 json:"-"`
+	RecoveryCodes []string   `bson:"recovery_codes"         json:"-"`
+	LastStep      int64      `bson:"last_step"              json:"-"` // ultimo periodo usado, un codigo no sirve dos veces
+	ConfirmedAt   *time.Time `bson:"confirmed_at,omitempty" json:"confirmed_at,omitempty"`
+}
+
+func (u *User) TwoFactorEnabled() bool {
+	return u.TwoFactor != nil && u.TwoFactor.ConfirmedAt != nil
+}
+
+// EnrollTwoFactor crea un secreto nuevo sin confirmar, retorna el secreto en claro para la app de autenticacion
+func (u *User) EnrollTwoFactor() (string, app.Error) {
+	if u.TwoFactorEnabled() {
+		return "", app.Errors.BadRequestf("Two-factor authentication is already enabled.")
+	}
+	secret, err := app.TOTPSecret()
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := app.Encrypt(secret)
+	if err != nil {
+		return "", err
+	}
+	u.TwoFactor = &TwoFactor{Secret: encrypted, RecoveryCodes: []string{}}
+	if err := u.UpdateFields(map[string]any{"two_factor": u.TwoFactor}); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ConfirmTwoFactor valida el primer codigo, activa el 2FA y retorna los codigos de recuperacion en claro
+func (u *User) ConfirmTwoFactor(code string) ([]string, app.Error) {
+	if u.TwoFactor == nil {
+		return nil, app.Errors.BadRequestf("Two-factor authentication has not been started.")
+	}
+	if u.TwoFactorEnabled() {
+		return nil, app.Errors.BadRequestf("Two-factor authentication is already enabled.")
+	}
+	if err := u.verifyTOTP(code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := u.UpdateFields(map[string]any{
+		"two_factor.recovery_codes": hashes,
+		"two_factor.confirmed_at":   now,
+	}); err != nil {
+		return nil, err
+	}
+	u.TwoFactor.RecoveryCodes = hashes
+	u.TwoFactor.ConfirmedAt = &now
+	return codes, nil
+}
+
+// DisableTwoFactor elimina el secreto y los codigos de recuperacion
+func (u *User) DisableTwoFactor() app.Error {
+	if err := u.UpdateOne(Filter(Where("_id", Eq(u.ID))), Unset("two_factor")); err != nil {
+		return err
+	}
+	u.TwoFactor = nil
+	return nil
+}
+
+// VerifyTwoFactor acepta un codigo TOTP o uno de recuperacion, el de recuperacion se elimina al usarlo
+func (u *User) VerifyTwoFactor(code string) app.Error {
+	if !u.TwoFactorEnabled() {
+		return app.Errors.BadRequestf("Two-factor authentication is not enabled.")
+	}
+	if len(strings.TrimSpace(code)) == app.TOTP_DIGITS {
+		return u.verifyTOTP(code)
+	}
+
+	// el filtro con el hash hace que dos peticiones con el mismo codigo no pasen las dos
+	hash := HashToken(normalizeRecoveryCode(code))
+	if err := u.UpdateOne(
+		Filter(Where("_id", Eq(u.ID)), Where("two_factor.recovery_codes", Eq(hash))),
+		Pull(Element("two_factor.recovery_codes", hash)),
+	); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return app.Errors.Unauthorizedf("The two-factor code is invalid.")
+		}
+		return err
+	}
+	go HistoryRecord(u.ID, u, "use-recovery-code", nil)
+	return nil
+}
+
+func (u *User) verifyTOTP(code string) app.Error {
+	invalid := app.Errors.Unauthorizedf("The two-factor code is invalid.")
+	secret, err := app.Decrypt(u.TwoFactor.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := app.TOTPVerify(secret, code, time.Now(), u.TwoFactor.LastStep)
+	if !ok {
+		return invalid
+	}
+	// se guarda el periodo solo si es mayor al ultimo, asi el mismo codigo no entra dos veces en paralelo
+	if err := u.UpdateOne(
+		Filter(Where("_id", Eq(u.ID)), Where("two_factor.last_step", Lt(step))),
+		Set(Element("two_factor.last_step", step)),
+	); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return invalid
+		}
+		return err
+	}
+	u.TwoFactor.LastStep = step
+	return nil
+}
+
+// newRecoveryCodes retorna los codigos en claro (xxxxxxxx-xxxxxxxx-xxxxxxxx-xxxxxxxx) y sus hash para guardar
+func newRecoveryCodes() ([]string, []string, app.Error) {
+	codes := make([]string, 0, TWO_FACTOR_RECOVERY_CODES)
+	hashes := make([]string, 0, TWO_FACTOR_RECOVERY_CODES)
+	for range TWO_FACTOR_RECOVERY_CODES {
+		bytes := make([]byte, TWO_FACTOR_RECOVERY_CODE_BYTES)
+		if _, er := rand.Read(bytes); er != nil {
+			return nil, nil, app.Errors.InternalServerError(er)
+		}
+		code := hex.EncodeToString(bytes)
+		groups := []string{}
+		for i := 0; i < len(code); i += 8 {
+			groups = append(groups, code[i:i+8])
+		}
+		codes = append(codes, strings.Join(groups, "-"))
+		hashes = append(hashes, HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -1,21 +1,48 @@
 package model
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	. "github.com/donbarrigon/nuevo-proyecto/internal/app/qb"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// login sin contraseña, el correo trae un enlace con Code y un codigo de 6 digitos, sirve el primero que se use
+const MAGIC_LOGIN = "magic-login"
+
+// minutos de vida del enlace y del codigo de 6 digitos
+const MAGIC_LOGIN_LIFETIME = 15
+
+// intentos con codigo de 6 digitos equivocado por usuario en MAGIC_LOGIN_LIFETIME, pedir otro correo no los reinicia
+const MAGIC_LOGIN_MAX_ATTEMPTS = 5
+
+// tipo del contador que limita los correos de login sin contraseña a uno por usuario cada MAGIC_LOGIN_COOLDOWN segundos
+const MAGIC_LOGIN_REQUEST = "magic-login-request"
+const MAGIC_LOGIN_COOLDOWN = 60
+
+// sufijo del tipo del contador de intentos del usuario (ej: two-factor-login-attempts), ver ReserveAttempt
+const ATTEMPTS_SUFFIX = "-attempts"
+
 type VerificationCode struct {
 	ID        bson.ObjectID     `bson:"_id,omitempty"      json:"id,omitempty"`
 	UserID    bson.ObjectID     `bson:"user_id"            json:"user_id"`
 	Type      string            `bson:"type"               json:"type"`
 	Code      string            `bson:"code"               json:"code"`
+	Token     string            `bson:"-"                  json:"-"` // en claro, solo despues de GenerateHashed
 	Metadata  map[string]string `bson:"metadata,omitempty" json:"metadata,omitempty"`
 	UsedAt    *time.Time        `bson:"used_at,omitempty"  json:"used_at,omitempty"`
+	Attempts  int               `bson:"attempts,omitempty" json:"attempts,omitempty"` // intentos fallidos, para los codigos que se pueden adivinar
 	CreatedAt time.Time         `bson:"created_at"         json:"created_at"`
 	ExpiresAt time.Time         `bson:"expires_at"         json:"updatexpires_ated_at"`
 
@@ -32,21 +59,156 @@ func (v *VerificationCode) CollectionName() string { return "verification_codes"
 func (v *VerificationCode) GetID() bson.ObjectID   { return v.ID }
 func (v *VerificationCode) SetID(id bson.ObjectID) { v.ID = id }
 
+func (v *VerificationCode) Indexes() []app.ModelIndex {
+	return []app.ModelIndex{
+		app.UniqueIndex(1, "user_id", "type", "code"),
+	}
+}
+
 func (v *VerificationCode) BeforeCreate() app.Error {
 	v.CreatedAt = time.Now()
-	v.ExpiresAt = time.Now().Add(1 * time.Hour)
+	if v.ExpiresAt.IsZero() {
+		v.ExpiresAt = time.Now().Add(1 * time.Hour)
+	}
 	return nil
 }
 func (v *VerificationCode) BeforeUpdate() app.Error { return nil }
 
+// Consume marca el codigo como usado, el filtro con used_at nil hace que solo una peticion lo pueda usar
+func (v *VerificationCode) Consume() app.Error {
+	now := time.Now()
+	if err := v.UpdateOne(
+		Filter(Where("_id", Eq(v.ID)), Where("used_at", Eq(nil))),
+		Set(Element("used_at", now)),
+	); err != nil {
+		if err.GetStatus() == http.StatusNotFound {
+			return app.Errors.Unauthorizedf("The code has already been used.")
+		}
+		return err
+	}
+	v.UsedAt = &now
+	return nil
+}
+
+// Usable indica si el codigo no se ha usado y no ha vencido
+func (v *VerificationCode) Usable() bool {
+	return v.UsedAt == nil && v.ExpiresAt.After(time.Now())
+}
+
+// GenerateOTP retorna un codigo de 6 digitos en claro y guarda su hash en metadata["otp"]
+func (v *VerificationCode) GenerateOTP() (string, app.Error) {
+	number, er := rand.Int(rand.Reader, big.NewInt(1000000))
+	if er != nil {
+		return "", app.Errors.InternalServerError(er)
+	}
+	otp := fmt.Sprintf("%06d", number.Int64())
+	if v.Metadata == nil {
+		v.Metadata = map[string]string{}
+	}
+	v.Metadata["otp"] = HashToken(otp)
+	return otp, nil
+}
+
+// VerifyOTP compara el codigo con el hash de metadata["otp"]
+func (v *VerificationCode) VerifyOTP(otp string) bool {
+	hash, ok := v.Metadata["otp"]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(HashToken(strings.TrimSpace(otp)))) == 1
+}
+
+// ReserveAttempt cuenta un intento del usuario antes de comparar un codigo que se puede adivinar (6 digitos).
+// El contador es por usuario y no por codigo, pedir otro codigo no lo reinicia. Es un solo FindOneAndUpdate
+// con attempts < max para que las peticiones en paralelo no pasen del limite. Al acertar se llama ResetAttempts,
+// si no el contador se descarta cuando pasa window
+func ReserveAttempt(userID bson.ObjectID, codeType string, maxAttempts int, window time.Duration) app.Error {
+	tooMany := app.Errors.TooManyRequestsf("Too many failed attempts, try again later.")
+	collection := app.DB.Collection(NewVerificationCode().CollectionName())
+	counter := func(conditions ...bson.E) bson.D {
+		return append(Filter(
+			Where("user_id", Eq(userID)),
+			Where("type", Eq(codeType+ATTEMPTS_SUFFIX)),
+			Where("code", Eq("")),
+		), conditions...)
+	}
+
+	// puede que no exista, no se usa Odm.DeleteMany
+	if _, er := collection.DeleteMany(context.TODO(), counter(Where("expires_at", Lt(time.Now())))); er != nil {
+		return app.Errors.Mongo(er)
+	}
+
+	now := time.Now()
+	update := append(
+		Inc(Element("attempts", 1)),
+		SetOnInsert(Element("created_at", now), Element("expires_at", now.Add(window)))...,
+	)
+	// dos vueltas por si otra peticion crea el contador al mismo tiempo y el upsert choca con el indice unico
+	for range 2 {
+		attempt := NewVerificationCode()
+		er := collection.FindOneAndUpdate(context.TODO(),
+			counter(Where("attempts", Lt(maxAttempts))),
+			update,
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(attempt)
+		if er == nil {
+			if attempt.Attempts > maxAttempts {
+				return tooMany
+			}
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(er) {
+			return app.Errors.Mongo(er)
+		}
+		// el contador ya existe y no paso el filtro, si ya llego al limite no hay mas intentos
+		current := NewVerificationCode()
+		if err := current.FindOne(counter()); err == nil && current.Attempts >= maxAttempts {
+			return tooMany
+		}
+	}
+	return tooMany
+}
+
+// ResetAttempts borra el contador de ReserveAttempt cuando el codigo es correcto
+func ResetAttempts(userID bson.ObjectID, codeType string) app.Error {
+	if _, er := app.DB.Collection(NewVerificationCode().CollectionName()).DeleteMany(context.TODO(), Filter(
+		Where("user_id", Eq(userID)),
+		Where("type", Eq(codeType+ATTEMPTS_SUFFIX)),
+	)); er != nil {
+		return app.Errors.Mongo(er)
+	}
+	return nil
+}
+
 func (v *VerificationCode) Generate(id bson.ObjectID, t string, metadata ...map[string]string) app.Error {
-	bytes := make([]byte, 32)
-	if _, err := rand.Read(bytes); err != nil {
-		app.PrintWarning("Fail to create verification code: " + err.Error())
-		return app.Errors.InternalServerError(err)
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
 	}
-	code := hex.EncodeToString(bytes)
+	return v.create(id, t, code, metadata...)
+}
+
+// GenerateHashed igual que Generate pero en mongo solo queda el hash del codigo y el codigo en claro queda en Token.
+// Para los codigos que entregan una sesion (login sin contraseña, segundo paso del 2FA), se buscan con FindByCode
+func (v *VerificationCode) GenerateHashed(id bson.ObjectID, t string, metadata ...map[string]string) app.Error {
+	code, err := newVerificationCode()
+	if err != nil {
+		return err
+	}
+	v.Token = code
+	return v.create(id, t, HashToken(code), metadata...)
+}
+
+// FindByCode busca el codigo de GenerateHashed por su hash
+func (v *VerificationCode) FindByCode(id bson.ObjectID, t string, code string) app.Error {
+	return v.FindOne(Filter(
+		Where("user_id", Eq(id)),
+		Where("type", Eq(t)),
+		Where("code", Eq(HashToken(code))),
+	))
+}
 
+func (v *VerificationCode) create(id bson.ObjectID, t string, code string, metadata ...map[string]string) app.Error {
 	if len(metadata) > 0 {
 		v.Metadata = metadata[0]
 	} else {
@@ -59,3 +221,12 @@ func (v *VerificationCode) Generate(id bson.ObjectID, t string, metadata ...map[
 
 	return v.Create()
 }
+
+func newVerificationCode() (string, app.Error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		app.PrintWarning("Fail to create verification code: " + err.Error())
+		return "", app.Errors.InternalServerError(err)
+	}
+	return hex.EncodeToString(bytes), nil
+}
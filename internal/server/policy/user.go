@@ -11,18 +11,26 @@ func UserViewAny(ctx *app.HttpContext) app.Error {
 }
 
 func UserView(ctx *app.HttpContext, user *model.User) app.Error {
-	if ctx.Auth.GetUserID() == user.ID {
+	if isSelf(ctx, user) {
 		return nil
 	}
 	return ctx.Auth.Can("view user")
 }
 
+// UserViewSessions las sesiones tienen la ip y el navegador de cada dispositivo, solo las ve el dueño
+func UserViewSessions(ctx *app.HttpContext, user *model.User) app.Error {
+	if isSelf(ctx, user) {
+		return nil
+	}
+	return app.Errors.Forbiddenf("access denied: only the owner can view the access tokens")
+}
+
 func UserCreate(ctx *app.HttpContext) app.Error {
 	return nil
 }
 
 func UserUpdate(ctx *app.HttpContext, user *model.User) app.Error {
-	if ctx.Auth.GetUserID() == user.ID {
+	if isSelf(ctx, user) {
 		return nil
 	}
 	return ctx.Auth.Can("update user")
@@ -31,3 +39,12 @@ func UserUpdate(ctx *app.HttpContext, user *model.User) app.Error {
 func UserDelete(ctx *app.HttpContext) app.Error {
 	return ctx.Auth.Can("delete user")
 }
+
+// isSelf el usuario sobre su propia cuenta no necesita permisos, con un token de API si
+// para que el token no pueda mas de lo que se le dio al crearlo
+func isSelf(ctx *app.HttpContext, user *model.User) bool {
+	if token, ok := ctx.Auth.(*model.AccessToken); ok && token.IsAPI() {
+		return false
+	}
+	return ctx.Auth.GetUserID() == user.ID
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+	"github.com/donbarrigon/nuevo-proyecto/internal/server/model"
+)
+
+// SendEmailMagicLogin envia el enlace y el codigo de 6 digitos para entrar sin contraseña, los dos sirven una sola vez
+func SendEmailMagicLogin(user *model.User, deviceName string) {
+	magicCode := model.NewVerificationCode()
+	magicCode.ExpiresAt = time.Now().Add(model.MAGIC_LOGIN_LIFETIME * time.Minute)
+	otp, err := magicCode.GenerateOTP()
+	if err != nil {
+		app.PrintError("Failed to generate magic login code", app.E("error", err))
+		return
+	}
+	magicCode.Metadata["device_name"] = deviceName
+	if err := magicCode.GenerateHashed(user.ID, model.MAGIC_LOGIN, magicCode.Metadata); err != nil {
+		app.PrintError("Failed to generate magic login code", app.E("error", err))
+		return
+	}
+
+	if app.Env.APP_LOCALE == "es" {
+		sendEmailMagicLoginEs(user, magicCode, otp)
+	} else {
+		sendEmailMagicLoginEn(user, magicCode, otp)
+	}
+}
+
+func sendEmailMagicLoginEs(user *model.User, magicCode *model.VerificationCode, otp string) {
+	link := app.Env.APP_URL + `/users/login/magic/` + user.ID.Hex() + `/` + magicCode.Token
+
+	subject := "Tu enlace para iniciar sesion en " + app.Env.APP_NAME
+
+	body := `
+    <h1>Hola ` + user.Profile.Nickname + `</h1>
+    <p>Recibimos una solicitud para iniciar sesion en ` + app.Env.APP_NAME + ` sin contraseña.</p>
+    <p>
+        <a href="` + link + `" 
+           style="display:inline-block;padding:10px 20px;background:#007bff;color:#fff;
+                  text-decoration:none;border-radius:5px;">
+           Iniciar sesion
+        </a>
+    </p>
+    <p>O escribe este codigo en la aplicacion:</p>
+    <h2>` + otp + `</h2>
+    <p>El enlace y el codigo vencen en ` + strconv.Itoa(model.MAGIC_LOGIN_LIFETIME) + ` minutos y solo se pueden usar una vez.</p>
+    <p>Si no solicitaste iniciar sesion, simplemente ignora este mensaje.</p>
+    <p>Si no puedes hacer clic, copia y pega este enlace en tu navegador:</p>
+    <p>` + link + `</p>
+    <br>
+    <p>Equipo de ` + app.Env.APP_NAME + `</p>
+    `
+
+	SendMail(subject, body, user.Email)
+}
+
+func sendEmailMagicLoginEn(user *model.User, magicCode *model.VerificationCode, otp string) {
+	link := app.Env.APP_URL + `/users/login/magic/` + user.ID.Hex() + `/` + magicCode.Token
+
+	subject := "Your sign in link for " + app.Env.APP_NAME
+
+	body := `
+    <h1>Hello ` + user.Profile.Nickname + `</h1>
+    <p>We received a request to sign in to ` + app.Env.APP_NAME + ` without a password.</p>
+    <p>
+        <a href="` + link + `" 
+           style="display:inline-block;padding:10px 20px;background:#007bff;color:#fff;
+                  text-decoration:none;border-radius:5px;">
+           Sign in
+        </a>
+    </p>
+    <p>Or enter this code in the app:</p>
+    <h2>` + otp + `</h2>
+    <p>The link and the code expire in ` + strconv.Itoa(model.MAGIC_LOGIN_LIFETIME) + ` minutes and can only be used once.</p>
+    <p>If you did not request to sign in, simply ignore this email.</p>
+    <p>If you cannot click the button, copy and paste this link into your browser:</p>
+    <p>` + link + `</p>
+    <br>
+    <p>The ` + app.Env.APP_NAME + ` Team</p>
+    `
+
+	SendMail(subject, body, user.Email)
+}
@@ -0,0 +1,15 @@
+package validator
+
+import (
+	"time"
+
+	"github.com/donbarrigon/nuevo-proyecto/internal/app"
+)
+
+type StoreAPIToken struct {
+	Name        string    `json:"name"                 rules:"required|max:100"`
+	Permissions []string  `json:"permissions"          rules:"required"`           // deben ser parte de los permisos del usuario
+	ExpiresAt   time.Time `json:"expires_at,omitempty" rules:"nullable|after_now"` // si no viene el token no vence
+}
+
+func (v *StoreAPIToken) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
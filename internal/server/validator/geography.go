@@ -0,0 +1,102 @@
+package validator
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+// la ubicacion se recibe como latitude y longitude y el controlador arma el GeoPoint.
+// En los Update todo es opcional, solo se cambia lo que venga y las coordenadas son punteros por que 0 es valido
+
+type StoreCountry struct {
+	Name           string            `json:"name"                      rules:"required|max:255"`
+	Iso2           string            `json:"iso2"                      rules:"required|uppercase|min:2|max:2|unique:countries,iso2"`
+	Iso3           string            `json:"iso3"                      rules:"required|uppercase|min:3|max:3|unique:countries,iso3"`
+	NumericCode    string            `json:"numeric_code,omitempty"    rules:"nullable|digits:3"`
+	PhoneCode      string            `json:"phonecode,omitempty"       rules:"nullable|max:32"`
+	Capital        string            `json:"capital,omitempty"         rules:"nullable|max:255"`
+	Currency       string            `json:"currency,omitempty"        rules:"nullable|max:8"`
+	CurrencyName   string            `json:"currency_name,omitempty"   rules:"nullable|max:255"`
+	CurrencySymbol string            `json:"currency_symbol,omitempty" rules:"nullable|max:16"`
+	TLD            string            `json:"tld,omitempty"             rules:"nullable|max:16"`
+	Native         string            `json:"native,omitempty"          rules:"nullable|max:255"`
+	Nationality    string            `json:"nationality,omitempty"     rules:"nullable|max:255"`
+	Emoji          string            `json:"emoji,omitempty"           rules:"nullable|max:16"`
+	Translations   map[string]string `json:"translations,omitempty"`
+	Latitude       float64           `json:"latitude,omitempty"        rules:"nullable|between:-90,90"`
+	Longitude      float64           `json:"longitude,omitempty"       rules:"nullable|between:-180,180"`
+}
+
+func (v *StoreCountry) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type UpdateCountry struct {
+	Name           string            `json:"name,omitempty"            rules:"nullable|max:255"`
+	Iso2           string            `json:"iso2,omitempty"            rules:"nullable|uppercase|min:2|max:2|unique:countries,iso2"`
+	Iso3           string            `json:"iso3,omitempty"            rules:"nullable|uppercase|min:3|max:3|unique:countries,iso3"`
+	NumericCode    string            `json:"numeric_code,omitempty"    rules:"nullable|digits:3"`
+	PhoneCode      string            `json:"phonecode,omitempty"       rules:"nullable|max:32"`
+	Capital        string            `json:"capital,omitempty"         rules:"nullable|max:255"`
+	Currency       string            `json:"currency,omitempty"        rules:"nullable|max:8"`
+	CurrencyName   string            `json:"currency_name,omitempty"   rules:"nullable|max:255"`
+	CurrencySymbol string            `json:"currency_symbol,omitempty" rules:"nullable|max:16"`
+	TLD            string            `json:"tld,omitempty"             rules:"nullable|max:16"`
+	Native         string            `json:"native,omitempty"          rules:"nullable|max:255"`
+	Nationality    string            `json:"nationality,omitempty"     rules:"nullable|max:255"`
+	Emoji          string            `json:"emoji,omitempty"           rules:"nullable|max:16"`
+	Translations   map[string]string `json:"translations,omitempty"`
+	Latitude       *float64          `json:"latitude,omitempty"        rules:"nullable|between:-90,90"`
+	Longitude      *float64          `json:"longitude,omitempty"       rules:"nullable|between:-180,180"`
+}
+
+func (v *UpdateCountry) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type StoreState struct {
+	Name      string  `json:"name"                rules:"required|max:255"`
+	CountryID string  `json:"country_id"          rules:"required|exists:countries,_id"`
+	Iso2      string  `json:"iso2,omitempty"      rules:"nullable|max:16"`
+	Iso3166_2 string  `json:"iso3166_2,omitempty" rules:"nullable|max:16"`
+	FipsCode  string  `json:"fips_code,omitempty" rules:"nullable|max:16"`
+	Type      string  `json:"type,omitempty"      rules:"nullable|max:64"`
+	Level     int     `json:"level,omitempty"     rules:"nullable|positive"`
+	ParentID  string  `json:"parent_id,omitempty" rules:"nullable|exists:states,_id"`
+	Timezone  string  `json:"timezone,omitempty"  rules:"nullable|max:64"`
+	Latitude  float64 `json:"latitude,omitempty"  rules:"nullable|between:-90,90"`
+	Longitude float64 `json:"longitude,omitempty" rules:"nullable|between:-180,180"`
+}
+
+func (v *StoreState) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type UpdateState struct {
+	Name      string   `json:"name,omitempty"       rules:"nullable|max:255"`
+	CountryID string   `json:"country_id,omitempty" rules:"nullable|exists:countries,_id"`
+	Iso2      string   `json:"iso2,omitempty"       rules:"nullable|max:16"`
+	Iso3166_2 string   `json:"iso3166_2,omitempty"  rules:"nullable|max:16"`
+	FipsCode  string   `json:"fips_code,omitempty"  rules:"nullable|max:16"`
+	Type      string   `json:"type,omitempty"       rules:"nullable|max:64"`
+	Level     int      `json:"level,omitempty"      rules:"nullable|positive"`
+	ParentID  string   `json:"parent_id,omitempty"  rules:"nullable|exists:states,_id"`
+	Timezone  string   `json:"timezone,omitempty"   rules:"nullable|max:64"`
+	Latitude  *float64 `json:"latitude,omitempty"   rules:"nullable|between:-90,90"`
+	Longitude *float64 `json:"longitude,omitempty"  rules:"nullable|between:-180,180"`
+}
+
+func (v *UpdateState) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type StoreCity struct {
+	Name       string  `json:"name"                 rules:"required|max:255"`
+	StateID    string  `json:"state_id"             rules:"required|exists:states,_id"`
+	Timezone   string  `json:"timezone,omitempty"   rules:"nullable|max:64"`
+	WikiDataID string  `json:"wikiDataId,omitempty" rules:"nullable|max:32"`
+	Latitude   float64 `json:"latitude,omitempty"   rules:"nullable|between:-90,90"`
+	Longitude  float64 `json:"longitude,omitempty"  rules:"nullable|between:-180,180"`
+}
+
+func (v *StoreCity) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type UpdateCity struct {
+	Name       string   `json:"name,omitempty"       rules:"nullable|max:255"`
+	StateID    string   `json:"state_id,omitempty"   rules:"nullable|exists:states,_id"`
+	Timezone   string   `json:"timezone,omitempty"   rules:"nullable|max:64"`
+	WikiDataID string   `json:"wikiDataId,omitempty" rules:"nullable|max:32"`
+	Latitude   *float64 `json:"latitude,omitempty"   rules:"nullable|between:-90,90"`
+	Longitude  *float64 `json:"longitude,omitempty"  rules:"nullable|between:-180,180"`
+}
+
+func (v *UpdateCity) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
@@ -0,0 +1,23 @@
+package validator
+
+import "github.com/donbarrigon/nuevo-proyecto/internal/app"
+
+type TwoFactorCode struct {
+	Code string `json:"code" rules:"required|max:32"` // TOTP o codigo de recuperacion
+}
+
+func (v *TwoFactorCode) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type DisableTwoFactor struct {
+	Password string `json:"password" rules:"required"`
+	Code     string `json:"code"     rules:"required|max:32"`
+}
+
+func (v *DisableTwoFactor) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type LoginTwoFactor struct {
+	TwoFactorToken string `json:"two_factor_token" rules:"required|max:128"`
+	Code           string `json:"code"             rules:"required|max:32"`
+}
+
+func (v *LoginTwoFactor) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
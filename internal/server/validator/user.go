@@ -5,15 +5,16 @@ import (
 )
 
 type StoreUser struct {
-	Email                string         `json:"email"                      rules:"required|max:255|email|unique:users,email"`
-	Password             string         `json:"password"                   rules:"reqired|confirmed|min:8|max:32"`
-	PasswordConfirmation string         `json:"password_confirmation"`
-	FullName             string         `json:"full_name,omitempty"        rules:"max:255|alpha_spaces_accents"`
-	Nickname             string         `json:"nickname"                   rules:"required|username|max:255|unique:users,profile.nickname"`
-	PhoneNumber          string         `json:"phone_number,omitempty"     rules:"max:255|regex:^\\+[1-9]\\d{1,14}$"`
-	DiscordUsername      string         `json:"discord_username,omitempty" rules:"max:255"`
-	CityID               string         `json:"city_id"                    rules:"required|max:255|exists:cities,_id"`
-	Preferences          map[string]any `json:"preferences,omitempty"`
+	Email                string `json:"email"                      rules:"required|max:255|email|unique:users,email"`
+	Password             string `json:"password"                   rules:"reqired|confirmed|min:8|max:32"`
+	PasswordConfirmation string `json:"password_confirmation"`
+	FullName             string `json:"full_name,omitempty"        rules:"max:255|alpha_spaces_accents"`
+	Nickname             string `json:"nickname"                   rules:"required|username|max:255|unique:users,profile.nickname"`
+	PhoneNumber          string `json:"phone_number,omitempty"     rules:"max:255|regex:^\\+[1-9]\\d{1,14}$"`
+	DiscordUsername      string `json:"discord_username,omitempty" rules:"max:255"`
+	CityID               string `json:"city_id"                    rules:"required|max:255|exists:cities,_id"`
+	Timezone             string `json:"timezone,omitempty"         rules:"nullable|timezone"` // si no viene se usa la de la ciudad
+	Locale               string `json:"locale,omitempty"           rules:"nullable|locale"`
 }
 
 func (v *StoreUser) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
@@ -34,25 +35,57 @@ type UpdateUserPassword struct {
 func (v *UpdateUserPassword) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
 
 type UpdateUserProfile struct {
-	FullName        string         `json:"full_name,omitempty"        rules:"max:255|alpha_spaces_accents"`
-	Nickname        string         `json:"nickname"                   rules:"required|username|max:255|unique:users,profile.nickname"`
-	PhoneNumber     string         `json:"phone_number,omitempty"     rules:"max:255|regex:^\\+[1-9]\\d{1,14}$"`
-	DiscordUsername string         `json:"discord_username,omitempty" rules:"max:255"`
-	CityID          string         `json:"city_id"                    rules:"required|max:255|exists:cities,_id"`
-	Preferences     map[string]any `json:"preferences,omitempty"`
+	FullName        string `json:"full_name,omitempty"        rules:"max:255|alpha_spaces_accents"`
+	Nickname        string `json:"nickname"                   rules:"required|username|max:255|unique:users,profile.nickname"`
+	PhoneNumber     string `json:"phone_number,omitempty"     rules:"max:255|regex:^\\+[1-9]\\d{1,14}$"`
+	DiscordUsername string `json:"discord_username,omitempty" rules:"max:255"`
+	CityID          string `json:"city_id"                    rules:"required|max:255|exists:cities,_id"`
 }
 
 func (v *UpdateUserProfile) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
 
+// los campos que no vienen conservan el valor actual
+type UpdateUserPreferences struct {
+	Timezone          string `json:"timezone,omitempty"           rules:"nullable|timezone"`
+	Locale            string `json:"locale,omitempty"             rules:"nullable|locale"`
+	DateFormat        string `json:"date_format,omitempty"        rules:"nullable|date_format"`
+	NotifyByEmail     *bool  `json:"notify_by_email,omitempty"`
+	NotifyByPush      *bool  `json:"notify_by_push,omitempty"`
+	NotifyByMarketing *bool  `json:"notify_by_marketing,omitempty"`
+}
+
+func (v *UpdateUserPreferences) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
 type UserLogin struct {
-	Email    string `json:"email"    rules:"required|email"`
-	Password string `json:"password" rules:"required"`
+	Email      string `json:"email"                 rules:"required|email"`
+	Password   string `json:"password"              rules:"required"`
+	DeviceName string `json:"device_name,omitempty" rules:"nullable|max:100"` // si no viene se usa el user agent
 }
 
 func (v *UserLogin) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
 
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" rules:"required"`
+}
+
+func (v *RefreshToken) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
 type ForgotPassword struct {
 	Email string `json:"email" rules:"required|max:255|email"`
 }
 
 func (v *ForgotPassword) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type MagicLogin struct {
+	Email      string `json:"email"                 rules:"required|max:255|email"`
+	DeviceName string `json:"device_name,omitempty" rules:"nullable|max:100"`
+}
+
+func (v *MagicLogin) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }
+
+type MagicLoginCode struct {
+	Email string `json:"email" rules:"required|max:255|email"`
+	Code  string `json:"code"  rules:"required|digits:6"`
+}
+
+func (v *MagicLoginCode) PrepareForValidation(ctx *app.HttpContext) app.Error { return nil }